@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/spf13/viper"
 )
@@ -10,21 +11,31 @@ import (
 // Config 配置结构
 type Config struct {
 	Server struct {
-		Port           int    `mapstructure:"port"`
-		Host           string `mapstructure:"host"`
-		MaxConnections int    `mapstructure:"max_connections"`
-		ReadTimeout    int    `mapstructure:"read_timeout"`
-		WebSocket      struct {
+		Port            int    `mapstructure:"port"`
+		Host            string `mapstructure:"host"`
+		MaxConnections  int    `mapstructure:"max_connections"`
+		ReadTimeout     int    `mapstructure:"read_timeout"`
+		ShutdownTimeout int    `mapstructure:"shutdown_timeout"`
+		WebSocket       struct {
 			ReadTimeout       int  `mapstructure:"read_timeout"`
 			MaxMessageSize    int  `mapstructure:"max_message_size"`
 			ReadBufferSize    int  `mapstructure:"read_buffer_size"`
 			WriteBufferSize   int  `mapstructure:"write_buffer_size"`
 			EnableCompression bool `mapstructure:"enable_compression"`
 		} `mapstructure:"websocket"`
+		TLS TLSConf `mapstructure:"tls"`
 	} `mapstructure:"server"`
 	Session struct {
-		SendQueueSize int `mapstructure:"send_queue_size"`
-		MaxSendErrors int `mapstructure:"max_send_errors"`
+		SendQueueSize        int `mapstructure:"send_queue_size"`
+		MaxSendErrors        int `mapstructure:"max_send_errors"`
+		IdleTimeoutSeconds   int `mapstructure:"idle_timeout_seconds"`
+		MaxSessionAgeSeconds int `mapstructure:"max_session_age_seconds"`
+
+		// ResumeSecret非空时开启WebSocket重连复用(HMAC签发/校验resume_token)；
+		// 为空则RemoveSession保持此前"立即销毁"的行为
+		ResumeSecret             string `mapstructure:"resume_secret"`
+		ResumeTTLSeconds         int    `mapstructure:"resume_ttl_seconds"`
+		ResumeGracePeriodSeconds int    `mapstructure:"resume_grace_period_seconds"`
 	} `mapstructure:"session"`
 	VAD         VADConfig `mapstructure:"vad"`
 	Recognition struct {
@@ -37,12 +48,22 @@ type Config struct {
 		Debug                       bool   `mapstructure:"debug"`
 	} `mapstructure:"recognition"`
 	Speaker struct {
-		Enabled    bool    `mapstructure:"enabled"`
-		ModelPath  string  `mapstructure:"model_path"`
-		NumThreads int     `mapstructure:"num_threads"`
-		Provider   string  `mapstructure:"provider"`
-		Threshold  float32 `mapstructure:"threshold"`
-		DataDir    string  `mapstructure:"data_dir"`
+		Enabled       bool    `mapstructure:"enabled"`
+		ModelPath     string  `mapstructure:"model_path"`
+		NumThreads    int     `mapstructure:"num_threads"`
+		Provider      string  `mapstructure:"provider"`
+		Threshold     float32 `mapstructure:"threshold"`
+		DataDir       string  `mapstructure:"data_dir"`
+		StorageDriver string  `mapstructure:"storage_driver"`
+		DSN           string  `mapstructure:"dsn"`
+		ANNEnabled    bool    `mapstructure:"ann_enabled"`
+		ANNM          int     `mapstructure:"ann_m"`
+		ANNEfSearch   int     `mapstructure:"ann_ef_search"`
+
+		MinVoicedSeconds       float32 `mapstructure:"min_voiced_seconds"`
+		MinSNRDb               float32 `mapstructure:"min_snr_db"`
+		MaxClippingRatio       float32 `mapstructure:"max_clipping_ratio"`
+		MaxDuplicateSimilarity float32 `mapstructure:"max_duplicate_similarity"`
 	} `mapstructure:"speaker"`
 	Audio struct {
 		SampleRate      int     `mapstructure:"sample_rate"`
@@ -61,9 +82,13 @@ type Config struct {
 		BurstSize         int  `mapstructure:"burst_size"`
 		MaxConnections    int  `mapstructure:"max_connections"`
 	} `mapstructure:"rate_limit"`
-	Response struct {
-		SendMode string `mapstructure:"send_mode"`
-		Timeout  int    `mapstructure:"timeout"`
+	ConnectionLimits ConnectionLimitsConf `mapstructure:"connection_limits"`
+	Response         struct {
+		SendMode          string `mapstructure:"send_mode"`
+		Timeout           int    `mapstructure:"timeout"`
+		EmitPartials      bool   `mapstructure:"emit_partials"`
+		PartialIntervalMs int    `mapstructure:"partial_interval_ms"`
+		EnableBargeIn     bool   `mapstructure:"enable_barge_in"`
 	} `mapstructure:"response"`
 	Logging struct {
 		Level      string `mapstructure:"level"`
@@ -75,14 +100,65 @@ type Config struct {
 		MaxAge     int    `mapstructure:"max_age"`
 		Compress   bool   `mapstructure:"compress"`
 	} `mapstructure:"logging"`
+	GRPC struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Port    int    `mapstructure:"port"`
+		Host    string `mapstructure:"host"`
+	} `mapstructure:"grpc"`
+	Recorder struct {
+		Enabled          bool   `mapstructure:"enabled"`
+		OutputDir        string `mapstructure:"output_dir"`
+		Format           string `mapstructure:"format"`
+		MaxSize          int64  `mapstructure:"max_size"`
+		MaxAgeDays       int    `mapstructure:"max_age_days"`
+		IncludePreRollMs int    `mapstructure:"include_pre_roll_ms"`
+	} `mapstructure:"recorder"`
+	HotReload struct {
+		RemoteProvider      string `mapstructure:"remote_provider"` // ""/etcd3/consul/configmap，为空时只监听本地文件
+		RemoteEndpoint      string `mapstructure:"remote_endpoint"`
+		RemotePath          string `mapstructure:"remote_path"`
+		PollIntervalSeconds int    `mapstructure:"poll_interval_seconds"`
+	} `mapstructure:"hot_reload"`
+	Observability struct {
+		Enabled      bool   `mapstructure:"enabled"`       // 为false时不初始化OTel TracerProvider，internal/tracing退化为no-op
+		ServiceName  string `mapstructure:"service_name"`  // 上报到OTLP的service.name资源属性，为空时使用"asr_server"
+		OTLPEndpoint string `mapstructure:"otlp_endpoint"` // OTLP gRPC collector地址，如"localhost:4317"
+	} `mapstructure:"observability"`
+}
+
+// ConnectionLimitsConf 配置WebSocket升级路径上的准入控制：按IP/CIDR子网限制在途连接数，
+// 外加一份允许/拒绝CIDR名单与Origin白名单，防止单个客户端耗尽VAD池
+type ConnectionLimitsConf struct {
+	Enabled         bool     `mapstructure:"enabled"`
+	MaxPerIP        int      `mapstructure:"max_per_ip"`         // 单个IP允许的最大在途连接数，<=0表示不限制
+	MaxPerCIDR      int      `mapstructure:"max_per_cidr"`       // 单个CIDR分桶允许的最大在途连接数，<=0表示不限制
+	CIDRPrefixLenV4 int      `mapstructure:"cidr_prefix_len_v4"` // IPv4分桶前缀长度，<=0表示不做CIDR聚合统计
+	CIDRPrefixLenV6 int      `mapstructure:"cidr_prefix_len_v6"` // IPv6分桶前缀长度，<=0表示不做CIDR聚合统计
+	AllowCIDRs      []string `mapstructure:"allow_cidrs"`        // 非空时只允许落在其中的IP连接，其余一律拒绝
+	DenyCIDRs       []string `mapstructure:"deny_cidrs"`         // 命中即拒绝，优先级高于AllowCIDRs
+	AllowedOrigins  []string `mapstructure:"allowed_origins"`    // Origin白名单，为空表示不限制来源（保持历史行为）
+}
+
+// TLSConf 配置server.tls：证书/私钥路径、可选的客户端CA（非空即开启双向TLS）、
+// 最低TLS版本与密码套件白名单；Enabled为false时HandleWebSocket退化为明文ws
+type TLSConf struct {
+	Enabled           bool     `mapstructure:"enabled"`
+	CertFile          string   `mapstructure:"cert_file"`
+	KeyFile           string   `mapstructure:"key_file"`
+	ClientCAFile      string   `mapstructure:"client_ca_file"`      // 非空时校验客户端证书，开启mTLS
+	RequireClientCert bool     `mapstructure:"require_client_cert"` // 仅在ClientCAFile非空时生效；false则客户端证书可选
+	MinVersion        string   `mapstructure:"min_version"`         // "1.2"/"1.3"，为空时默认TLS 1.2
+	CipherSuites      []string `mapstructure:"cipher_suites"`       // crypto/tls标准套件名，为空时使用Go默认列表
 }
 
 type VADConfig struct {
-	Provider  string        `mapstructure:"provider"`
+	Provider  string        `mapstructure:"provider"` // silero_vad/ten_vad/webrtc_vad/energy_vad/auto
 	PoolSize  int           `mapstructure:"pool_size"`
 	Threshold float32       `mapstructure:"threshold"`
 	SileroVAD SileroVADConf `mapstructure:"silero_vad"`
 	TenVAD    TenVADConf    `mapstructure:"ten_vad"`
+	WebRTCVAD WebRTCVADConf `mapstructure:"webrtc_vad"`
+	EnergyVAD EnergyVADConf `mapstructure:"energy_vad"`
 }
 
 type SileroVADConf struct {
@@ -93,6 +169,10 @@ type SileroVADConf struct {
 	MaxSpeechDuration  float32 `mapstructure:"max_speech_duration"`
 	WindowSize         int     `mapstructure:"window_size"`
 	BufferSizeSeconds  float32 `mapstructure:"buffer_size_seconds"`
+
+	// MaxTempInstances上限池耗尽时Get()临时创建的额外实例数，<=0时套用内置默认值；
+	// 此前这条路径是完全不受限的，池压力大时可能无限创建ONNX实例拖垮进程
+	MaxTempInstances int `mapstructure:"max_temp_instances"`
 }
 
 type TenVADConf struct {
@@ -101,7 +181,42 @@ type TenVADConf struct {
 	MaxSilenceFrames int `mapstructure:"max_silence_frames"`
 }
 
-var GlobalConfig Config
+// WebRTCVADConf 配置纯Go实现的WebRTC风格VAD（帧能量+频谱平坦度gating）
+type WebRTCVADConf struct {
+	Aggressiveness     int     `mapstructure:"aggressiveness"` // 0-3，越大越保守（越不容易判定为语音）
+	FrameDurationMs    int     `mapstructure:"frame_duration_ms"`
+	MinSpeechDuration  float32 `mapstructure:"min_speech_duration"`
+	MaxSpeechDuration  float32 `mapstructure:"max_speech_duration"`
+	MinSilenceDuration float32 `mapstructure:"min_silence_duration"`
+}
+
+// EnergyVADConf 配置RMS/ZCR能量VAD，适用于信噪比较高的受控环境
+type EnergyVADConf struct {
+	RMSThreshold       float32 `mapstructure:"rms_threshold"`
+	ZCRThreshold       float32 `mapstructure:"zcr_threshold"`
+	FrameDurationMs    int     `mapstructure:"frame_duration_ms"`
+	MinSpeechDuration  float32 `mapstructure:"min_speech_duration"`
+	MaxSpeechDuration  float32 `mapstructure:"max_speech_duration"`
+	MinSilenceDuration float32 `mapstructure:"min_silence_duration"`
+}
+
+// current 以原子指针持有当前生效的配置快照；HotReloadManager的事务性reload通过
+// Store整体替换指针，Load的调用方永远只会读到完整的一份配置，不会读到正在被
+// viper.Unmarshal写入中的半成品
+var current atomic.Value // stores *Config
+
+// Load 返回当前生效的配置快照。InitConfig调用前返回一份零值配置
+func Load() *Config {
+	if cfg, ok := current.Load().(*Config); ok {
+		return cfg
+	}
+	return &Config{}
+}
+
+// Store 原子替换当前生效的配置，供InitConfig及HotReloadManager的事务性reload使用
+func Store(cfg *Config) {
+	current.Store(cfg)
+}
 
 // InitConfig 初始化配置
 func InitConfig(configPath string) error {
@@ -135,9 +250,11 @@ func InitConfig(configPath string) error {
 	}
 
 	// 将配置解析到结构体
-	if err := viper.Unmarshal(&GlobalConfig); err != nil {
+	cfg := &Config{}
+	if err := viper.Unmarshal(cfg); err != nil {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	Store(cfg)
 
 	return nil
 }
@@ -147,9 +264,9 @@ func LoadConfig(filename string) error {
 	return InitConfig(filename)
 }
 
-// GetConfig 获取配置
+// GetConfig 获取配置（等价于Load，保留是为了兼容既有调用方）
 func GetConfig() *Config {
-	return &GlobalConfig
+	return Load()
 }
 
 // GetViper 获取viper实例
@@ -176,7 +293,10 @@ func SaveConfigAs(filename string) error {
 func SetConfigValue(key string, value interface{}) {
 	viper.Set(key, value)
 	// 重新解析到结构体
-	viper.Unmarshal(&GlobalConfig)
+	cfg := &Config{}
+	if err := viper.Unmarshal(cfg); err == nil {
+		Store(cfg)
+	}
 }
 
 // GetConfigValue 获取配置值
@@ -206,12 +326,13 @@ func GetFloat64(key string) float64 {
 
 // PrintConfig 打印当前配置
 func PrintConfig() {
+	cfg := Load()
 	fmt.Println("📋 Current Configuration:")
-	fmt.Printf("  Server: %s:%d\n", GlobalConfig.Server.Host, GlobalConfig.Server.Port)
-	fmt.Printf("  VAD Model: %s\n", GlobalConfig.VAD.SileroVAD.ModelPath)
-	fmt.Printf("  ASR Model: %s\n", GlobalConfig.Recognition.ModelPath)
-	fmt.Printf("  Pool Workers: %d\n", GlobalConfig.Pool.WorkerCount)
-	fmt.Printf("  VAD Pool Size: %d\n", GlobalConfig.VAD.PoolSize)
-	fmt.Printf("  Log Level: %s\n", GlobalConfig.Logging.Level)
-	fmt.Printf("  Log Output: %s\n", GlobalConfig.Logging.FilePath)
+	fmt.Printf("  Server: %s:%d\n", cfg.Server.Host, cfg.Server.Port)
+	fmt.Printf("  VAD Model: %s\n", cfg.VAD.SileroVAD.ModelPath)
+	fmt.Printf("  ASR Model: %s\n", cfg.Recognition.ModelPath)
+	fmt.Printf("  Pool Workers: %d\n", cfg.Pool.WorkerCount)
+	fmt.Printf("  VAD Pool Size: %d\n", cfg.VAD.PoolSize)
+	fmt.Printf("  Log Level: %s\n", cfg.Logging.Level)
+	fmt.Printf("  Log Output: %s\n", cfg.Logging.FilePath)
 }
@@ -0,0 +1,22 @@
+package asr
+
+// Result 识别结果，与具体识别后端解耦，便于在测试中注入mock实现
+type Result struct {
+	Text string
+}
+
+// Stream 承载一次解码所需的音频缓冲区，由Recognizer创建，使用完毕后必须调用Close
+// 释放其持有的资源（对sherpa等cgo后端而言通常对应native内存）
+type Stream interface {
+	AcceptWaveform(sampleRate int, samples []float32)
+	GetResult() *Result
+	Close()
+}
+
+// Recognizer 抽象一个语音识别后端，session包仅依赖本接口，不直接依赖任何具体引擎，
+// 使得whisper.cpp/funasr等其他后端可以按相同接口接入而无需改动session包内部逻辑
+type Recognizer interface {
+	NewStream() Stream
+	Decode(Stream)
+	Close()
+}
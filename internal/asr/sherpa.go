@@ -0,0 +1,52 @@
+package asr
+
+import (
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// sherpaOfflineRecognizer 将*sherpa.OfflineRecognizer适配为Recognizer接口，
+// 是目前唯一的生产实现
+type sherpaOfflineRecognizer struct {
+	recognizer *sherpa.OfflineRecognizer
+}
+
+// NewSherpaOfflineRecognizer 包装一个已创建好的sherpa离线识别器
+func NewSherpaOfflineRecognizer(recognizer *sherpa.OfflineRecognizer) Recognizer {
+	return &sherpaOfflineRecognizer{recognizer: recognizer}
+}
+
+func (r *sherpaOfflineRecognizer) NewStream() Stream {
+	return &sherpaOfflineStream{stream: sherpa.NewOfflineStream(r.recognizer)}
+}
+
+func (r *sherpaOfflineRecognizer) Decode(stream Stream) {
+	s, ok := stream.(*sherpaOfflineStream)
+	if !ok {
+		return
+	}
+	r.recognizer.Decode(s.stream)
+}
+
+// Close sherpa的OfflineRecognizer没有显式释放API，其生命周期与进程一致
+func (r *sherpaOfflineRecognizer) Close() {}
+
+// sherpaOfflineStream 适配*sherpa.OfflineStream
+type sherpaOfflineStream struct {
+	stream *sherpa.OfflineStream
+}
+
+func (s *sherpaOfflineStream) AcceptWaveform(sampleRate int, samples []float32) {
+	s.stream.AcceptWaveform(sampleRate, samples)
+}
+
+func (s *sherpaOfflineStream) GetResult() *Result {
+	result := s.stream.GetResult()
+	if result == nil {
+		return nil
+	}
+	return &Result{Text: result.Text}
+}
+
+func (s *sherpaOfflineStream) Close() {
+	sherpa.DeleteOfflineStream(s.stream)
+}
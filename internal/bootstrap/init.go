@@ -1,174 +1,357 @@
-package bootstrap
-
-import (
-	"fmt"
-	"os"
-
-	"asr_server/config"
-	"asr_server/internal/config/hotreload"
-	"asr_server/internal/logger"
-	"asr_server/internal/middleware"
-	"asr_server/internal/pool"
-	"asr_server/internal/session"
-	"asr_server/internal/speaker"
-
-	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
-)
-
-type AppDependencies struct {
-	SessionManager   *session.Manager
-	VADPool          pool.VADPoolInterface
-	RateLimiter      *middleware.RateLimiter
-	SpeakerManager   *speaker.Manager
-	SpeakerHandler   *speaker.Handler
-	GlobalRecognizer *sherpa.OfflineRecognizer
-	HotReloadMgr     *hotreload.HotReloadManager
-}
-
-// createRecognizer 用于初始化 sherpa 识别器
-func createRecognizer(cfg *config.Config) (*sherpa.OfflineRecognizer, error) {
-	c := sherpa.OfflineRecognizerConfig{}
-	c.FeatConfig.SampleRate = cfg.Audio.SampleRate
-	c.FeatConfig.FeatureDim = cfg.Audio.FeatureDim
-
-	c.ModelConfig.SenseVoice.Model = cfg.Recognition.ModelPath
-	c.ModelConfig.Tokens = cfg.Recognition.TokensPath
-	c.ModelConfig.NumThreads = cfg.Recognition.NumThreads
-	c.ModelConfig.Debug = 0
-	if cfg.Recognition.Debug {
-		c.ModelConfig.Debug = 1
-	}
-	c.ModelConfig.Provider = cfg.Recognition.Provider
-
-	recognizer := sherpa.NewOfflineRecognizer(&c)
-	if recognizer == nil {
-		return nil, fmt.Errorf("failed to create offline recognizer")
-	}
-
-	return recognizer, nil
-}
-
-// registerHotReloadCallbacks 注册配置热加载回调
-func registerHotReloadCallbacks(hotReloadMgr *hotreload.HotReloadManager) {
-	if hotReloadMgr == nil {
-		return
-	}
-
-	hotReloadMgr.RegisterCallback("logging.level", func() {
-		logger.Infof("🔄 Log level changed to: %s", config.GlobalConfig.Logging.Level)
-	})
-	hotReloadMgr.RegisterCallback("vad", func() {
-		logger.Infof("🔄 VAD configuration changed")
-	})
-	hotReloadMgr.RegisterCallback("session", func() {
-		logger.Infof("🔄 Session configuration changed")
-	})
-	hotReloadMgr.RegisterCallback("rate_limit", func() {
-		logger.Infof("🔄 Rate limit configuration changed")
-	})
-	hotReloadMgr.RegisterCallback("response", func() {
-		logger.Infof("🔄 Response configuration changed")
-	})
-	logger.Infof("✅ Hot reload callbacks registered")
-}
-
-// InitApp 初始化所有核心组件，返回依赖注入结构体
-func InitApp(cfg *config.Config) (*AppDependencies, error) {
-	logger.Infof("🔧 Initializing components...")
-
-	// 初始化配置热加载管理器
-	logger.Infof("🔧 Initializing hot reload manager...")
-	hotReloadMgr, err := hotreload.NewHotReloadManager()
-	if err != nil {
-		logger.Errorf("Failed to initialize hot reload manager: %v", err)
-		return nil, fmt.Errorf("failed to initialize hot reload manager: %v", err)
-	}
-	if err := hotReloadMgr.StartWatching("config.json"); err != nil {
-		logger.Warnf("Failed to start config file watching, continuing without hot reload: %v", err)
-	}
-
-	// 初始化全局识别器
-	logger.Infof("🔧 Initializing global recognizer...")
-	globalRecognizer, err := createRecognizer(cfg)
-	if err != nil {
-		logger.Errorf("Failed to initialize global recognizer: %v", err)
-		return nil, fmt.Errorf("failed to initialize global recognizer: %v", err)
-	}
-
-	// 根据VAD类型初始化VAD池
-	var vadPool pool.VADPoolInterface
-	vadFactory := pool.NewVADFactory()
-
-	if config.GlobalConfig.VAD.Provider == pool.SILERO_TYPE {
-		// 检查VAD模型文件是否存在（仅对silero需要）
-		if _, err := os.Stat(cfg.VAD.SileroVAD.ModelPath); os.IsNotExist(err) {
-			logger.Errorf("VAD model file not found, model_path=%s", cfg.VAD.SileroVAD.ModelPath)
-			return nil, fmt.Errorf("VAD model file not found: %s", cfg.VAD.SileroVAD.ModelPath)
-		}
-	}
-
-	// 使用工厂创建VAD池
-	vadPool, err = vadFactory.CreateVADPool()
-	if err != nil {
-		logger.Errorf("Failed to create VAD pool: %v", err)
-		return nil, fmt.Errorf("failed to create VAD pool: %v", err)
-	}
-
-	// 初始化VAD池
-	logger.Infof("🔧 Initializing VAD pool... pool_size=%d", cfg.VAD.PoolSize)
-	if err := vadPool.Initialize(); err != nil {
-		logger.Errorf("Failed to initialize VAD pool: %v", err)
-		return nil, fmt.Errorf("failed to initialize VAD pool: %v", err)
-	}
-
-	// 初始化会话管理器
-	logger.Infof("🔧 Initializing session manager...")
-	sessionManager := session.NewManager(globalRecognizer, vadPool)
-
-	// 注册配置热加载回调
-	registerHotReloadCallbacks(hotReloadMgr)
-
-	// 初始化速率限制器
-	logger.Infof("🔧 Initializing rate limiter... requests_per_second=%d, max_connections=%d", cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.MaxConnections)
-	rateLimiter := middleware.NewRateLimiter(
-		cfg.RateLimit.Enabled,
-		cfg.RateLimit.RequestsPerSecond,
-		cfg.RateLimit.BurstSize,
-		cfg.RateLimit.MaxConnections,
-	)
-
-	// 初始化声纹识别模块
-	var speakerManager *speaker.Manager
-	var speakerHandler *speaker.Handler
-	if cfg.Speaker.Enabled {
-		if _, statErr := os.Stat(cfg.Speaker.ModelPath); !os.IsNotExist(statErr) {
-			speakerConfig := &speaker.Config{
-				ModelPath:  cfg.Speaker.ModelPath,
-				NumThreads: cfg.Speaker.NumThreads,
-				Provider:   cfg.Speaker.Provider,
-				Threshold:  cfg.Speaker.Threshold,
-				DataDir:    cfg.Speaker.DataDir,
-			}
-			mgr, err := speaker.NewManager(speakerConfig)
-			if err == nil {
-				speakerManager = mgr
-				speakerHandler = speaker.NewHandler(speakerManager)
-			} else {
-				logger.Warnf("Failed to initialize speaker recognition module, continuing without it: %v", err)
-			}
-		} else {
-			logger.Warnf("Speaker model file not found, speaker recognition disabled, model_path=%s", cfg.Speaker.ModelPath)
-		}
-	}
-
-	logger.Infof("✅ All components initialized successfully")
-	return &AppDependencies{
-		SessionManager:   sessionManager,
-		VADPool:          vadPool,
-		RateLimiter:      rateLimiter,
-		SpeakerManager:   speakerManager,
-		SpeakerHandler:   speakerHandler,
-		GlobalRecognizer: globalRecognizer,
-		HotReloadMgr:     hotReloadMgr,
-	}, nil
-}
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"asr_server/config"
+	"asr_server/internal/asr"
+	"asr_server/internal/config/hotreload"
+	"asr_server/internal/logger"
+	"asr_server/internal/middleware"
+	"asr_server/internal/pool"
+	"asr_server/internal/recorder"
+	"asr_server/internal/session"
+	"asr_server/internal/speaker"
+	"asr_server/internal/tracing"
+	"asr_server/internal/transport"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// defaultRemotePollInterval 未配置hot_reload.poll_interval_seconds时的远程配置轮询周期
+const defaultRemotePollInterval = 30 * time.Second
+
+type AppDependencies struct {
+	SessionManager   *session.Manager
+	VADPool          pool.VADPoolInterface
+	RateLimiter      *middleware.RateLimiter
+	ConnLimiter      *middleware.Limiter
+	SpeakerManager   *speaker.Manager
+	SpeakerHandler   *speaker.Handler
+	GlobalRecognizer *sherpa.OfflineRecognizer
+	HotReloadMgr     *hotreload.HotReloadManager
+	Recorder         *recorder.Recorder
+	Listener         *transport.ListenerWrapper
+	State            *AppState
+	TracerShutdown   func(context.Context) error
+}
+
+// createRecognizer 用于初始化 sherpa 识别器
+func createRecognizer(cfg *config.Config) (*sherpa.OfflineRecognizer, error) {
+	c := sherpa.OfflineRecognizerConfig{}
+	c.FeatConfig.SampleRate = cfg.Audio.SampleRate
+	c.FeatConfig.FeatureDim = cfg.Audio.FeatureDim
+
+	c.ModelConfig.SenseVoice.Model = cfg.Recognition.ModelPath
+	c.ModelConfig.Tokens = cfg.Recognition.TokensPath
+	c.ModelConfig.NumThreads = cfg.Recognition.NumThreads
+	c.ModelConfig.Debug = 0
+	if cfg.Recognition.Debug {
+		c.ModelConfig.Debug = 1
+	}
+	c.ModelConfig.Provider = cfg.Recognition.Provider
+
+	recognizer := sherpa.NewOfflineRecognizer(&c)
+	if recognizer == nil {
+		return nil, fmt.Errorf("failed to create offline recognizer")
+	}
+
+	return recognizer, nil
+}
+
+// registerHotReloadCallbacks 注册配置热加载回调。回调返回error会使本次reload整体回滚，
+// 因此这里只应放那些"应用新配置"的动作是否成功是可判定的逻辑；仅打日志的回调恒返回nil
+func registerHotReloadCallbacks(hotReloadMgr *hotreload.HotReloadManager, audioRecorder *recorder.Recorder, listener *transport.ListenerWrapper) {
+	if hotReloadMgr == nil {
+		return
+	}
+
+	hotReloadMgr.RegisterCallback("logging.level", func() error {
+		logger.Infof("🔄 Log level changed to: %s", config.Load().Logging.Level)
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("vad", func() error {
+		logger.Infof("🔄 VAD configuration changed")
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("session", func() error {
+		logger.Infof("🔄 Session configuration changed")
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("rate_limit", func() error {
+		logger.Infof("🔄 Rate limit configuration changed")
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("response", func() error {
+		logger.Infof("🔄 Response configuration changed")
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("recorder", func() error {
+		// 仅切换开关；output_dir/format/max_size等其余字段需要重启才能生效
+		audioRecorder.SetEnabled(config.Load().Recorder.Enabled)
+		return nil
+	})
+	hotReloadMgr.RegisterCallback("server.tls", func() error {
+		// 证书校验已经在registerConfigValidators的"server.tls"校验器里做过一遍，
+		// 这里理论上不会再失败；仍按error处理以便触发reload的事务性回滚
+		newTLSConfig, err := transport.BuildTLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to rebuild TLS config: %w", err)
+		}
+		listener.Store(newTLSConfig)
+		logger.Infof("🔄 TLS listener configuration reloaded, enabled=%v", listener.IsTLSEnabled())
+		return nil
+	})
+	logger.Infof("✅ Hot reload callbacks registered")
+}
+
+// registerConfigValidators 按子系统注册配置校验器，reload时在候选配置替换config.Load()返回的
+// 指针之前执行；任一校验失败都会保留此前生效的配置，不触发回调
+func registerConfigValidators(hotReloadMgr *hotreload.HotReloadManager) {
+	if hotReloadMgr == nil {
+		return
+	}
+
+	hotReloadMgr.RegisterValidator("vad", func(cfg *config.Config) error {
+		if cfg.VAD.Provider == pool.SILERO_TYPE && cfg.VAD.SileroVAD.ModelPath == "" {
+			return fmt.Errorf("vad.silero_vad.model_path must not be empty when vad.provider is %s", pool.SILERO_TYPE)
+		}
+		if cfg.VAD.PoolSize <= 0 {
+			return fmt.Errorf("vad.pool_size must be positive")
+		}
+		return nil
+	})
+	hotReloadMgr.RegisterValidator("recognition", func(cfg *config.Config) error {
+		if cfg.Recognition.ModelPath == "" || cfg.Recognition.TokensPath == "" {
+			return fmt.Errorf("recognition.model_path and recognition.tokens_path must not be empty")
+		}
+		return nil
+	})
+	hotReloadMgr.RegisterValidator("speaker", func(cfg *config.Config) error {
+		if cfg.Speaker.Enabled && cfg.Speaker.ModelPath == "" {
+			return fmt.Errorf("speaker.model_path must not be empty when speaker.enabled is true")
+		}
+		if cfg.Speaker.Threshold < 0 || cfg.Speaker.Threshold > 1 {
+			return fmt.Errorf("speaker.threshold must be within [0, 1]")
+		}
+		return nil
+	})
+	hotReloadMgr.RegisterValidator("pool", func(cfg *config.Config) error {
+		if cfg.Pool.WorkerCount <= 0 {
+			return fmt.Errorf("pool.worker_count must be positive")
+		}
+		return nil
+	})
+	hotReloadMgr.RegisterValidator("server.tls", func(cfg *config.Config) error {
+		if !cfg.Server.TLS.Enabled {
+			return nil
+		}
+		if cfg.Server.TLS.CertFile == "" || cfg.Server.TLS.KeyFile == "" {
+			return fmt.Errorf("server.tls.cert_file and server.tls.key_file must not be empty when server.tls.enabled is true")
+		}
+		if _, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile); err != nil {
+			return fmt.Errorf("failed to load server.tls certificate pair: %w", err)
+		}
+		return nil
+	})
+	logger.Infof("✅ Hot reload validators registered")
+}
+
+// InitApp 初始化所有核心组件，返回依赖注入结构体
+func InitApp(cfg *config.Config) (*AppDependencies, error) {
+	logger.Infof("🔧 Initializing components...")
+
+	// 初始化配置热加载管理器
+	logger.Infof("🔧 Initializing hot reload manager...")
+	hotReloadMgr, err := hotreload.NewHotReloadManager()
+	if err != nil {
+		logger.Errorf("Failed to initialize hot reload manager: %v", err)
+		return nil, fmt.Errorf("failed to initialize hot reload manager: %v", err)
+	}
+	if err := hotReloadMgr.StartWatching("config.json"); err != nil {
+		logger.Warnf("Failed to start config file watching, continuing without hot reload: %v", err)
+	}
+	registerConfigValidators(hotReloadMgr)
+
+	// 初始化OTel追踪：observability.enabled为false时tracerShutdown是no-op，
+	// HandleWebSocket/session.Manager里的tracing.Tracer()调用不需要关心这个开关
+	logger.Infof("🔧 Initializing tracing... enabled=%v", cfg.Observability.Enabled)
+	tracerShutdown, err := tracing.Init(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize tracing: %v", err)
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	// 除本地文件外，按配置可选接入远程配置源（etcd3/Consul，或Kubernetes ConfigMap的
+	// 文件系统投影），复用同一条防抖动reload链路
+	if cfg.HotReload.RemoteProvider != "" {
+		interval := time.Duration(cfg.HotReload.PollIntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = defaultRemotePollInterval
+		}
+
+		var source hotreload.ConfigSource
+		if cfg.HotReload.RemoteProvider == "configmap" {
+			source = hotreload.NewConfigMapSource(cfg.HotReload.RemotePath, interval)
+		} else {
+			source = hotreload.NewRemoteSource(cfg.HotReload.RemoteProvider, cfg.HotReload.RemoteEndpoint, cfg.HotReload.RemotePath, interval)
+		}
+
+		if err := hotReloadMgr.AddSource(source); err != nil {
+			logger.Warnf("Failed to start remote config source %s, continuing without it: %v", cfg.HotReload.RemoteProvider, err)
+		} else {
+			logger.Infof("🔍 Started watching remote config source: %s", cfg.HotReload.RemoteProvider)
+		}
+	}
+
+	// 初始化全局识别器
+	logger.Infof("🔧 Initializing global recognizer...")
+	globalRecognizer, err := createRecognizer(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize global recognizer: %v", err)
+		return nil, fmt.Errorf("failed to initialize global recognizer: %v", err)
+	}
+
+	// 根据VAD类型初始化VAD池
+	var vadPool pool.VADPoolInterface
+	vadFactory := pool.NewVADFactory()
+
+	if config.Load().VAD.Provider == pool.SILERO_TYPE {
+		// 检查VAD模型文件是否存在（仅对silero需要）
+		if _, err := os.Stat(cfg.VAD.SileroVAD.ModelPath); os.IsNotExist(err) {
+			logger.Errorf("VAD model file not found, model_path=%s", cfg.VAD.SileroVAD.ModelPath)
+			return nil, fmt.Errorf("VAD model file not found: %s", cfg.VAD.SileroVAD.ModelPath)
+		}
+	}
+
+	// 使用工厂创建VAD池
+	vadPool, err = vadFactory.CreateVADPool()
+	if err != nil {
+		logger.Errorf("Failed to create VAD pool: %v", err)
+		return nil, fmt.Errorf("failed to create VAD pool: %v", err)
+	}
+
+	// 初始化VAD池
+	logger.Infof("🔧 Initializing VAD pool... pool_size=%d", cfg.VAD.PoolSize)
+	if err := vadPool.Initialize(); err != nil {
+		logger.Errorf("Failed to initialize VAD pool: %v", err)
+		return nil, fmt.Errorf("failed to initialize VAD pool: %v", err)
+	}
+
+	// 初始化声纹识别模块（需在会话管理器之前创建，以便注入实时说话人分离）
+	var speakerManager *speaker.Manager
+	var speakerHandler *speaker.Handler
+	if cfg.Speaker.Enabled {
+		if _, statErr := os.Stat(cfg.Speaker.ModelPath); !os.IsNotExist(statErr) {
+			speakerConfig := &speaker.Config{
+				ModelPath:     cfg.Speaker.ModelPath,
+				NumThreads:    cfg.Speaker.NumThreads,
+				Provider:      cfg.Speaker.Provider,
+				Threshold:     cfg.Speaker.Threshold,
+				DataDir:       cfg.Speaker.DataDir,
+				StorageDriver: cfg.Speaker.StorageDriver,
+				DSN:           cfg.Speaker.DSN,
+				ANNEnabled:    cfg.Speaker.ANNEnabled,
+				ANNM:          cfg.Speaker.ANNM,
+				ANNEfSearch:   cfg.Speaker.ANNEfSearch,
+
+				MinVoicedSeconds:       cfg.Speaker.MinVoicedSeconds,
+				MinSNRDb:               cfg.Speaker.MinSNRDb,
+				MaxClippingRatio:       cfg.Speaker.MaxClippingRatio,
+				MaxDuplicateSimilarity: cfg.Speaker.MaxDuplicateSimilarity,
+			}
+			mgr, err := speaker.NewManager(speakerConfig)
+			if err == nil {
+				speakerManager = mgr
+				speakerHandler = speaker.NewHandler(speakerManager)
+			} else {
+				logger.Warnf("Failed to initialize speaker recognition module, continuing without it: %v", err)
+			}
+		} else {
+			logger.Warnf("Speaker model file not found, speaker recognition disabled, model_path=%s", cfg.Speaker.ModelPath)
+		}
+	}
+
+	// 初始化录音子系统：Enabled可通过"recorder"热加载回调在运行时切换，
+	// 因此无论初始开关状态都创建Recorder并接入SegmentSink，使toggle无需重启生效
+	logger.Infof("🔧 Initializing recorder... enabled=%v, format=%s", cfg.Recorder.Enabled, cfg.Recorder.Format)
+	audioRecorder := recorder.New(recorder.Config{
+		Enabled:          cfg.Recorder.Enabled,
+		OutputDir:        cfg.Recorder.OutputDir,
+		Format:           cfg.Recorder.Format,
+		MaxSizeBytes:     cfg.Recorder.MaxSize,
+		MaxAgeDays:       cfg.Recorder.MaxAgeDays,
+		IncludePreRollMs: cfg.Recorder.IncludePreRollMs,
+	})
+
+	// 初始化会话管理器：当前部署下所有会话共用同一个识别引擎与VAD池，
+	// 以功能选项的形式注入是为后续按语言路由识别引擎、A/B测试VAD策略等预留扩展点
+	logger.Infof("🔧 Initializing session manager...")
+	sharedRecognizer := asr.NewSherpaOfflineRecognizer(globalRecognizer)
+	sessionManager := session.NewManager(
+		session.WithRecognizerFactory(func(session.SessionMeta) asr.Recognizer { return sharedRecognizer }),
+		session.WithVADStrategy(func(session.SessionMeta) pool.VADPoolInterface { return vadPool }),
+		session.WithSpeakerManager(speakerManager),
+		session.WithSegmentSink(audioRecorder, cfg.Recorder.IncludePreRollMs),
+		session.WithResumption(
+			[]byte(cfg.Session.ResumeSecret),
+			time.Duration(cfg.Session.ResumeTTLSeconds)*time.Second,
+			time.Duration(cfg.Session.ResumeGracePeriodSeconds)*time.Second,
+		),
+	)
+
+	// 创建TCP监听器并包一层ListenerWrapper：TLS配置此刻就绑定，之后server.tls的
+	// 热加载回调只需原子替换ListenerWrapper内部的*tls.Config，不需要重新bind端口
+	listenAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	logger.Infof("🔧 Binding listener on %s...", listenAddr)
+	netListener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		logger.Errorf("Failed to listen on %s: %v", listenAddr, err)
+		return nil, fmt.Errorf("failed to listen on %s: %w", listenAddr, err)
+	}
+	initialTLSConfig, err := transport.BuildTLSConfig()
+	if err != nil {
+		logger.Errorf("Failed to build TLS config: %v", err)
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+	listener := transport.NewListenerWrapper(netListener, initialTLSConfig)
+	logger.Infof("🔐 TLS enabled=%v on %s", listener.IsTLSEnabled(), listenAddr)
+
+	// 注册配置热加载回调
+	registerHotReloadCallbacks(hotReloadMgr, audioRecorder, listener)
+
+	// 初始化速率限制器
+	logger.Infof("🔧 Initializing rate limiter... requests_per_second=%d, max_connections=%d", cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.MaxConnections)
+	rateLimiter := middleware.NewRateLimiter(
+		cfg.RateLimit.Enabled,
+		cfg.RateLimit.RequestsPerSecond,
+		cfg.RateLimit.BurstSize,
+		cfg.RateLimit.MaxConnections,
+	)
+
+	// 初始化WebSocket升级路径上的per-IP/CIDR准入控制；与rateLimiter职责不同——
+	// 后者限的是请求速率与总连接数，前者限的是单个客户端能占多少在途连接
+	logger.Infof("🔧 Initializing connection limiter... enabled=%v, max_per_ip=%d", cfg.ConnectionLimits.Enabled, cfg.ConnectionLimits.MaxPerIP)
+	connLimiter := middleware.NewLimiter()
+
+	logger.Infof("✅ All components initialized successfully")
+	return &AppDependencies{
+		SessionManager:   sessionManager,
+		VADPool:          vadPool,
+		RateLimiter:      rateLimiter,
+		ConnLimiter:      connLimiter,
+		SpeakerManager:   speakerManager,
+		SpeakerHandler:   speakerHandler,
+		GlobalRecognizer: globalRecognizer,
+		HotReloadMgr:     hotReloadMgr,
+		Recorder:         audioRecorder,
+		Listener:         listener,
+		State:            &AppState{}, // 零值StateStarting；main在server开始Serve前置为StateReady
+		TracerShutdown:   tracerShutdown,
+	}, nil
+}
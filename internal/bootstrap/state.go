@@ -0,0 +1,44 @@
+package bootstrap
+
+import "sync/atomic"
+
+// LifecycleState 描述AppDependencies当前所处的生命周期阶段
+type LifecycleState int32
+
+const (
+	StateStarting LifecycleState = iota // 依赖尚未全部初始化完成
+	StateReady                          // 正常对外提供服务
+	StateDraining                       // 收到关闭信号，正在等待在途WebSocket会话结束
+	StateStopped                        // cleanup已执行完毕
+)
+
+func (s LifecycleState) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// AppState 原子持有当前生命周期阶段；signal handler在关闭流程中写入，
+// /readyz等HTTP请求并发读取，零值即StateStarting
+type AppState struct {
+	v int32
+}
+
+// Load 返回当前生命周期阶段
+func (s *AppState) Load() LifecycleState {
+	return LifecycleState(atomic.LoadInt32(&s.v))
+}
+
+// Store 原子写入生命周期阶段
+func (s *AppState) Store(state LifecycleState) {
+	atomic.StoreInt32(&s.v, int32(state))
+}
@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"asr_server/internal/logger"
+)
+
+// goroutineDumpBufSize 初始goroutine栈缓冲区大小；runtime.Stack在缓冲区不够时会被截断，
+// 这里给一个较宽裕的起始值，不够时翻倍重试
+const goroutineDumpBufSize = 1 << 20 // 1MB
+
+// Trap 注册SIGINT/SIGTERM/SIGQUIT信号处理，替代main里原来的一次性处理器：
+//   - 第一次SIGINT/SIGTERM：异步执行cleanup，超过timeout未完成则强制退出
+//   - 第二次：记录警告并把截止时间收紧为从现在起timeout的一半（不晚于原deadline），
+//     给"确实卡住了"的操作者一个更快退出的途径
+//   - 第三次：跳过剩余cleanup，立即os.Exit(1)
+//   - SIGQUIT：把完整的goroutine栈dump写入日志输出，不触发退出，用于诊断卡死在
+//     哪个阶段（WebSocket会话排空、VAD池销毁等）
+//
+// cleanup应当是幂等的——Trap只调用一次，但调用方内部组合的各子系统Shutdown应自身可重入。
+func Trap(cleanup func(), timeout time.Duration) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	go func() {
+		var (
+			signalCount    int
+			done           = make(chan struct{})
+			deadline       <-chan time.Time // 收到第一次关闭信号前保持nil，select上永远不会就绪
+			shutdownStart  time.Time
+			activeDeadline time.Duration // 当前生效的截止时长，仅用于超时日志展示
+		)
+
+		for {
+			select {
+			case sig := <-sigCh:
+				if sig == syscall.SIGQUIT {
+					dumpGoroutines()
+					continue
+				}
+
+				signalCount++
+				switch signalCount {
+				case 1:
+					shutdownStart = time.Now()
+					activeDeadline = timeout
+					logger.Infof("🛑 Received %v, starting graceful shutdown (timeout=%s)...", sig, timeout)
+					deadline = time.After(timeout)
+					go func() {
+						cleanup()
+						close(done)
+					}()
+				case 2:
+					// 剩余时间取"原始deadline还剩多少"与"原始timeout的一半"中较短的一个，
+					// 按从shutdownStart算起的绝对截止时间收紧，而不是从现在开始重新倒数
+					// timeout/2——否则当第一次信号后已经过去的时间超过timeout的一半时，
+					// 新deadline反而比原来更晚，起不到"第二次信号更快退出"的效果
+					remaining := timeout - time.Since(shutdownStart)
+					if remaining < 0 {
+						remaining = 0
+					}
+					shortened := timeout / 2
+					if remaining < shortened {
+						shortened = remaining
+					}
+					logger.Warnf("⚠️  Received second %v during shutdown, shortening deadline to %s", sig, shortened)
+					activeDeadline = shortened
+					deadline = time.After(shortened)
+				default:
+					logger.Errorf("🛑 Received third %v, skipping remaining cleanup and forcing exit", sig)
+					os.Exit(1)
+				}
+
+			case <-done:
+				logger.Infof("✅ Graceful shutdown complete")
+				return
+
+			case <-deadline:
+				logger.Errorf("Graceful shutdown timed out after %s, forcing exit", activeDeadline)
+				os.Exit(1)
+			}
+		}
+	}()
+}
+
+// dumpGoroutines 将所有goroutine的完整栈写入日志输出，缓冲区不够容纳时翻倍重试
+func dumpGoroutines() {
+	buf := make([]byte, goroutineDumpBufSize)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	fmt.Fprintf(logger.Writer(), "🔍 SIGQUIT received, dumping %d goroutines:\n%s\n", runtime.NumGoroutine(), buf)
+}
@@ -0,0 +1,28 @@
+package codec
+
+// Decoder 将编码音频字节流解码为归一化的float32 PCM采样。
+// 同一个Decoder实例仅供单个会话在其生命周期内复用，不可跨会话共享，
+// 因为Opus/FLAC等格式的解码器本身带有帧间/流级别的内部状态。
+type Decoder interface {
+	// Decode 解码一帧（或一段自包含）编码数据，返回对应的float32采样
+	Decode(in []byte) ([]float32, error)
+	// SampleRate 返回解码器当前已知的原生采样率；对于首次Decode前原生采样率
+	// 尚未知晓的格式（如FLAC，需解析STREAMINFO），应返回0
+	SampleRate() int
+	// MaxFrameSamples 返回单次Decode调用可能产生的最大采样点数，
+	// 供调用方据此为float32Pool按需分配而非使用固定的ChunkSize
+	MaxFrameSamples() int
+	// Reset 清空解码器内部状态，用于会话复位或编解码器切换
+	Reset()
+}
+
+const (
+	PCM16LE  = "pcm_s16le"
+	PCMMuLaw = "pcm_mulaw"
+	PCMALaw  = "pcm_alaw"
+	Opus     = "opus"
+	FLAC     = "flac"
+)
+
+// DefaultCodec 握手未声明编解码器时使用的默认格式，与既有ProcessAudioData的行为保持一致
+const DefaultCodec = PCM16LE
@@ -0,0 +1,29 @@
+package codec
+
+import "fmt"
+
+// Encoder 将归一化的float32 PCM采样编码为目标格式的完整自包含文件内容，与Decoder对称，
+// 供recorder等落盘场景复用本包已有的格式支持
+type Encoder interface {
+	// Encode 将一段PCM采样编码为该格式的完整文件内容（含容器/流头部）
+	Encode(samples []float32, sampleRate int) ([]byte, error)
+	// Ext 返回该编码格式对应的文件扩展名（含点，如".wav"）
+	Ext() string
+}
+
+// encoderFactories 按格式名称注册的Encoder构造函数
+var encoderFactories = map[string]func() Encoder{
+	"wav":  newWAVEncoder,
+	"flac": newFLACEncoder,
+	"opus": newOpusEncoder,
+}
+
+// NewEncoder 按格式名称创建Encoder。flac当前仅注册了接口占位，
+// Encode调用会返回"尚未实现"错误，待mewkiz/flac具备稳定编码支持后再补全
+func NewEncoder(format string) (Encoder, error) {
+	ctor, exists := encoderFactories[format]
+	if !exists {
+		return nil, fmt.Errorf("unsupported recording format: %s", format)
+	}
+	return ctor(), nil
+}
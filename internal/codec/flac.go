@@ -0,0 +1,49 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/mewkiz/flac"
+)
+
+// flacDecoder 解码FLAC编码的归档音频。每次Decode调用接收一段自包含的FLAC流，
+// 原生采样率在解析STREAMINFO之前未知，SampleRate()在此之前返回0，
+// 由resamplingDecoder据此延后重采样判断
+type flacDecoder struct {
+	sampleRate int
+}
+
+func newFLACDecoder(sampleRate int) (Decoder, error) {
+	return &flacDecoder{}, nil
+}
+
+func (d *flacDecoder) Decode(in []byte) ([]float32, error) {
+	stream, err := flac.New(bytes.NewReader(in))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flac stream: %w", err)
+	}
+	defer stream.Close()
+
+	d.sampleRate = int(stream.Info.SampleRate)
+	maxValue := float32(int32(1) << (stream.Info.BitsPerSample - 1))
+
+	var out []float32
+	for {
+		frame, err := stream.ParseNext()
+		if err != nil {
+			break
+		}
+		subframe := frame.Subframes[0]
+		for _, sample := range subframe.Samples[:frame.BlockSize] {
+			out = append(out, float32(sample)/maxValue)
+		}
+	}
+	return out, nil
+}
+
+func (d *flacDecoder) SampleRate() int { return d.sampleRate }
+
+func (d *flacDecoder) MaxFrameSamples() int { return 65536 }
+
+func (d *flacDecoder) Reset() { d.sampleRate = 0 }
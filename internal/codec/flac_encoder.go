@@ -0,0 +1,16 @@
+package codec
+
+import "fmt"
+
+// flacEncoder 占位实现：github.com/mewkiz/flac目前只提供稳定的解码支持，
+// 注册该格式名是为了让recorder的format配置在三种格式间保持一致的错误处理路径，
+// 而不是在配置校验阶段就拒绝"flac"
+type flacEncoder struct{}
+
+func newFLACEncoder() Encoder { return &flacEncoder{} }
+
+func (e *flacEncoder) Ext() string { return ".flac" }
+
+func (e *flacEncoder) Encode(samples []float32, sampleRate int) ([]byte, error) {
+	return nil, fmt.Errorf("flac encoding is not yet implemented")
+}
@@ -0,0 +1,87 @@
+package codec
+
+import "asr_server/config"
+
+// muLawDecoder 解析G.711 µ-law编码音频，常见于北美/日本电话网络
+type muLawDecoder struct {
+	sampleRate int
+}
+
+func newMuLawDecoder(sampleRate int) (Decoder, error) {
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	return &muLawDecoder{sampleRate: sampleRate}, nil
+}
+
+func (d *muLawDecoder) Decode(in []byte) ([]float32, error) {
+	out := make([]float32, len(in))
+	normalizeFactor := config.Load().Audio.NormalizeFactor
+	for i, b := range in {
+		out[i] = float32(decodeMuLawSample(b)) / normalizeFactor
+	}
+	return out, nil
+}
+
+func (d *muLawDecoder) SampleRate() int      { return d.sampleRate }
+func (d *muLawDecoder) MaxFrameSamples() int { return 8192 }
+func (d *muLawDecoder) Reset()               {}
+
+// decodeMuLawSample 将单字节µ-law样本还原为16位线性PCM，算法见ITU-T G.711
+func decodeMuLawSample(b byte) int16 {
+	b = ^b
+	sign := b & 0x80
+	exponent := (b >> 4) & 0x07
+	mantissa := b & 0x0F
+
+	sample := (int32(mantissa)<<3 + 0x84) << exponent
+	sample -= 0x84
+	if sign != 0 {
+		sample = -sample
+	}
+	return int16(sample)
+}
+
+// aLawDecoder 解析G.711 A-law编码音频，常见于欧洲/国际电话网络
+type aLawDecoder struct {
+	sampleRate int
+}
+
+func newALawDecoder(sampleRate int) (Decoder, error) {
+	if sampleRate <= 0 {
+		sampleRate = 8000
+	}
+	return &aLawDecoder{sampleRate: sampleRate}, nil
+}
+
+func (d *aLawDecoder) Decode(in []byte) ([]float32, error) {
+	out := make([]float32, len(in))
+	normalizeFactor := config.Load().Audio.NormalizeFactor
+	for i, b := range in {
+		out[i] = float32(decodeALawSample(b)) / normalizeFactor
+	}
+	return out, nil
+}
+
+func (d *aLawDecoder) SampleRate() int      { return d.sampleRate }
+func (d *aLawDecoder) MaxFrameSamples() int { return 8192 }
+func (d *aLawDecoder) Reset()               {}
+
+// decodeALawSample 将单字节A-law样本还原为16位线性PCM，算法见ITU-T G.711
+func decodeALawSample(b byte) int16 {
+	b ^= 0x55
+	sign := b & 0x80
+	exponent := (b >> 4) & 0x07
+	mantissa := b & 0x0F
+
+	var sample int32
+	if exponent == 0 {
+		sample = (int32(mantissa) << 4) + 8
+	} else {
+		sample = ((int32(mantissa) << 4) + 0x108) << (exponent - 1)
+	}
+	if sign == 0 {
+		sample = -sample
+	}
+	return int16(sample)
+}
@@ -0,0 +1,46 @@
+package codec
+
+import (
+	"fmt"
+
+	"github.com/hraban/opus"
+)
+
+// opusDecoder 解码浏览器MediaRecorder常见的Opus音频，假设客户端已拆出原始Opus包
+// （每次Decode调用对应一个完整Opus包），不负责解封装WebM/Ogg容器
+type opusDecoder struct {
+	dec        *opus.Decoder
+	sampleRate int
+}
+
+func newOpusDecoder(sampleRate int) (Decoder, error) {
+	if sampleRate <= 0 {
+		sampleRate = 48000
+	}
+
+	dec, err := opus.NewDecoder(sampleRate, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+	}
+	return &opusDecoder{dec: dec, sampleRate: sampleRate}, nil
+}
+
+func (d *opusDecoder) Decode(in []byte) ([]float32, error) {
+	pcm := make([]float32, d.MaxFrameSamples())
+	n, err := d.dec.DecodeFloat32(in, pcm)
+	if err != nil {
+		return nil, fmt.Errorf("opus decode failed: %w", err)
+	}
+	return pcm[:n], nil
+}
+
+func (d *opusDecoder) SampleRate() int { return d.sampleRate }
+
+// MaxFrameSamples Opus单帧最长60ms
+func (d *opusDecoder) MaxFrameSamples() int {
+	return d.sampleRate * 60 / 1000
+}
+
+func (d *opusDecoder) Reset() {
+	d.dec.ResetState()
+}
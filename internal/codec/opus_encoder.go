@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hraban/opus"
+)
+
+// opusEncoder 将PCM采样编码为opus包序列，每个包以4字节大端长度前缀写入，
+// 与opusDecoder假设的"每次Decode对应一个完整Opus包"对称，不封装为WebM/Ogg容器
+type opusEncoder struct{}
+
+func newOpusEncoder() Encoder { return &opusEncoder{} }
+
+func (e *opusEncoder) Ext() string { return ".opus" }
+
+// opusFrameMs 固定使用20ms帧，是opus编码器支持的标准帧长之一
+const opusFrameMs = 20
+
+func (e *opusEncoder) Encode(samples []float32, sampleRate int) ([]byte, error) {
+	enc, err := opus.NewEncoder(sampleRate, 1, opus.AppVoIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opus encoder: %w", err)
+	}
+
+	frameSamples := sampleRate * opusFrameMs / 1000
+	if frameSamples <= 0 {
+		return nil, fmt.Errorf("unsupported sample rate for opus encoding: %d", sampleRate)
+	}
+
+	var buf bytes.Buffer
+	data := make([]byte, 4000)
+	for offset := 0; offset < len(samples); offset += frameSamples {
+		end := offset + frameSamples
+		if end > len(samples) {
+			end = len(samples)
+		}
+		frame := samples[offset:end]
+		if len(frame) < frameSamples {
+			padded := make([]float32, frameSamples)
+			copy(padded, frame)
+			frame = padded
+		}
+
+		n, err := enc.EncodeFloat32(frame, data)
+		if err != nil {
+			return nil, fmt.Errorf("opus encode failed: %w", err)
+		}
+		if err := binary.Write(&buf, binary.BigEndian, uint32(n)); err != nil {
+			return nil, fmt.Errorf("failed to write opus packet length: %w", err)
+		}
+		buf.Write(data[:n])
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+package codec
+
+import (
+	"fmt"
+
+	"asr_server/config"
+)
+
+// pcm16LEDecoder 解析16位小端PCM，等价于既有ProcessAudioData中裸转换逻辑的抽取版本
+type pcm16LEDecoder struct {
+	sampleRate int
+}
+
+func newPCM16LEDecoder(sampleRate int) (Decoder, error) {
+	return &pcm16LEDecoder{sampleRate: sampleRate}, nil
+}
+
+func (d *pcm16LEDecoder) Decode(in []byte) ([]float32, error) {
+	if len(in)%2 != 0 {
+		return nil, fmt.Errorf("invalid pcm_s16le data length: %d", len(in))
+	}
+
+	numSamples := len(in) / 2
+	out := make([]float32, numSamples)
+	normalizeFactor := config.Load().Audio.NormalizeFactor
+	for i := 0; i < numSamples; i++ {
+		sample := int16(in[i*2]) | int16(in[i*2+1])<<8
+		out[i] = float32(sample) / normalizeFactor
+	}
+	return out, nil
+}
+
+func (d *pcm16LEDecoder) SampleRate() int { return d.sampleRate }
+
+func (d *pcm16LEDecoder) MaxFrameSamples() int {
+	chunkSize := config.Load().Audio.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 4096
+	}
+	return chunkSize
+}
+
+func (d *pcm16LEDecoder) Reset() {}
@@ -0,0 +1,76 @@
+package codec
+
+import (
+	"fmt"
+
+	"asr_server/internal/logger"
+)
+
+// decoderCtor 按会话声明的原生采样率构造一个Decoder实例
+type decoderCtor func(sampleRate int) (Decoder, error)
+
+// Factory 根据编解码器名称创建Decoder，支持通过RegisterFactory扩展新的编码格式
+type Factory struct {
+	factories map[string]decoderCtor
+}
+
+// NewFactory 创建新的编解码器工厂，预注册内置支持的格式
+func NewFactory() *Factory {
+	factory := &Factory{factories: make(map[string]decoderCtor)}
+
+	factory.RegisterFactory(PCM16LE, newPCM16LEDecoder)
+	factory.RegisterFactory(PCMMuLaw, newMuLawDecoder)
+	factory.RegisterFactory(PCMALaw, newALawDecoder)
+	factory.RegisterFactory(Opus, newOpusDecoder)
+	factory.RegisterFactory(FLAC, newFLACDecoder)
+
+	return factory
+}
+
+// RegisterFactory 注册编解码器构造函数，用于扩展新的音频前端格式
+func (f *Factory) RegisterFactory(codecName string, ctor decoderCtor) {
+	f.factories[codecName] = ctor
+	logger.Infof("🔧 Registered codec factory for type: %s", codecName)
+}
+
+// CreateDecoder 按编解码器名称创建Decoder
+func (f *Factory) CreateDecoder(codecName string, sampleRate int) (Decoder, error) {
+	ctor, exists := f.factories[codecName]
+	if !exists {
+		return nil, fmt.Errorf("unsupported codec: %s", codecName)
+	}
+	return ctor(sampleRate)
+}
+
+// GetSupportedTypes 返回当前已注册的编解码器名称列表
+func (f *Factory) GetSupportedTypes() []string {
+	types := make([]string, 0, len(f.factories))
+	for name := range f.factories {
+		types = append(types, name)
+	}
+	return types
+}
+
+// NewDecoder 按编解码器名称创建Decoder，若其原生采样率与targetSampleRate
+// 不一致（或解码前尚未知晓，如FLAC），自动包装一层重采样
+func (f *Factory) NewDecoder(codecName string, nativeSampleRate, targetSampleRate int) (Decoder, error) {
+	if codecName == "" {
+		codecName = DefaultCodec
+	}
+	decoder, err := f.CreateDecoder(codecName, nativeSampleRate)
+	if err != nil {
+		return nil, err
+	}
+	if targetSampleRate <= 0 {
+		return decoder, nil
+	}
+	return newResamplingDecoder(decoder, targetSampleRate), nil
+}
+
+var defaultFactory = NewFactory()
+
+// NewDecoder 使用默认工厂创建Decoder，等价于 NewFactory().NewDecoder(...)，
+// 供未自定义WithDecoderRegistry的调用方使用
+func NewDecoder(codecName string, nativeSampleRate, targetSampleRate int) (Decoder, error) {
+	return defaultFactory.NewDecoder(codecName, nativeSampleRate, targetSampleRate)
+}
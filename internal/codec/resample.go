@@ -0,0 +1,70 @@
+package codec
+
+// resamplingDecoder 包装另一个Decoder，在其原生采样率与目标采样率不一致时做线性重采样；
+// 原生采样率在解码前尚未知晓的格式（如FLAC，SampleRate()返回0）会在每次Decode后重新判断
+type resamplingDecoder struct {
+	inner      Decoder
+	targetRate int
+}
+
+func newResamplingDecoder(inner Decoder, targetRate int) Decoder {
+	return &resamplingDecoder{inner: inner, targetRate: targetRate}
+}
+
+func (d *resamplingDecoder) Decode(in []byte) ([]float32, error) {
+	samples, err := d.inner.Decode(in)
+	if err != nil {
+		return nil, err
+	}
+
+	nativeRate := d.inner.SampleRate()
+	if nativeRate <= 0 || nativeRate == d.targetRate {
+		return samples, nil
+	}
+	return linearResample(samples, nativeRate, d.targetRate), nil
+}
+
+func (d *resamplingDecoder) SampleRate() int {
+	return d.targetRate
+}
+
+func (d *resamplingDecoder) MaxFrameSamples() int {
+	nativeRate := d.inner.SampleRate()
+	nativeMax := d.inner.MaxFrameSamples()
+	if nativeRate <= 0 || nativeRate == d.targetRate {
+		return nativeMax
+	}
+	return int(float64(nativeMax)*float64(d.targetRate)/float64(nativeRate)) + 1
+}
+
+func (d *resamplingDecoder) Reset() {
+	d.inner.Reset()
+}
+
+// linearResample 对单声道float32 PCM做线性插值重采样。精度低于soxr等专业重采样库，
+// 但足以覆盖浏览器Opus(48kHz)/FLAC等常见来源降采样到识别/VAD目标采样率的场景
+func linearResample(samples []float32, fromRate, toRate int) []float32 {
+	if fromRate <= 0 || toRate <= 0 || fromRate == toRate || len(samples) == 0 {
+		return samples
+	}
+
+	ratio := float64(fromRate) / float64(toRate)
+	outLen := int(float64(len(samples)) / ratio)
+	if outLen <= 0 {
+		return nil
+	}
+
+	out := make([]float32, outLen)
+	lastIdx := len(samples) - 1
+	for i := range out {
+		srcPos := float64(i) * ratio
+		idx := int(srcPos)
+		if idx >= lastIdx {
+			out[i] = samples[lastIdx]
+			continue
+		}
+		frac := float32(srcPos - float64(idx))
+		out[i] = samples[idx]*(1-frac) + samples[idx+1]*frac
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+
+	"asr_server/config"
+)
+
+// memWriteSeeker 实现io.WriteSeeker的最小内存缓冲区。wav.Encoder在Close()时会回跳
+// 写入头部的长度字段，为此提供一个可寻址的内存缓冲而非落地临时文件
+type memWriteSeeker struct {
+	buf []byte
+	pos int
+}
+
+func (w *memWriteSeeker) Write(p []byte) (int, error) {
+	end := w.pos + len(p)
+	if end > len(w.buf) {
+		grown := make([]byte, end)
+		copy(grown, w.buf)
+		w.buf = grown
+	}
+	copy(w.buf[w.pos:end], p)
+	w.pos = end
+	return len(p), nil
+}
+
+func (w *memWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	var newPos int
+	switch whence {
+	case io.SeekStart:
+		newPos = int(offset)
+	case io.SeekCurrent:
+		newPos = w.pos + int(offset)
+	case io.SeekEnd:
+		newPos = len(w.buf) + int(offset)
+	default:
+		return 0, fmt.Errorf("unsupported seek whence: %d", whence)
+	}
+	if newPos < 0 {
+		return 0, fmt.Errorf("negative seek position")
+	}
+	w.pos = newPos
+	return int64(newPos), nil
+}
+
+// wavEncoder 将PCM采样编码为16位小端WAV文件
+type wavEncoder struct{}
+
+func newWAVEncoder() Encoder { return &wavEncoder{} }
+
+func (e *wavEncoder) Ext() string { return ".wav" }
+
+func (e *wavEncoder) Encode(samples []float32, sampleRate int) ([]byte, error) {
+	const bitDepth = 16
+
+	ws := &memWriteSeeker{}
+	enc := wav.NewEncoder(ws, sampleRate, bitDepth, 1, 1)
+
+	normalizeFactor := config.Load().Audio.NormalizeFactor
+	intSamples := make([]int, len(samples))
+	for i, s := range samples {
+		intSamples[i] = int(s * normalizeFactor)
+	}
+
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: sampleRate},
+		Data:           intSamples,
+		SourceBitDepth: bitDepth,
+	}
+	if err := enc.Write(buf); err != nil {
+		return nil, fmt.Errorf("failed to write wav samples: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize wav encoder: %w", err)
+	}
+
+	return ws.buf, nil
+}
@@ -12,10 +12,24 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Validator 校验一份候选配置是否可以安全生效，按子系统（VAD/Recognition/Speaker/Pool等）
+// 分别注册；任一校验器返回error都会中止本次reload，原配置保持不变
+type Validator func(*config.Config) error
+
+// ConfigSource 描述一个可驱动HotReloadManager重载的配置来源：本地文件或远程KV存储。
+// Watch应自行开goroutine监听变化并非阻塞返回；变化发生时调用notify，复用
+// handleConfigChange既有的防抖动重载路径，而不是各自实现一套重载逻辑
+type ConfigSource interface {
+	Watch(notify func()) error
+	Stop()
+}
+
 // HotReloadManager 配置热加载管理器
 type HotReloadManager struct {
 	mu            sync.RWMutex
-	callbacks     map[string][]func()
+	callbacks     map[string][]func() error
+	validators    map[string][]Validator
+	sources       []ConfigSource
 	watcher       *fsnotify.Watcher
 	debounceTimer *time.Timer
 	stopChan      chan struct{}
@@ -29,26 +43,47 @@ func NewHotReloadManager() (*HotReloadManager, error) {
 	}
 
 	manager := &HotReloadManager{
-		callbacks: make(map[string][]func()),
-		watcher:   watcher,
-		stopChan:  make(chan struct{}),
+		callbacks:  make(map[string][]func() error),
+		validators: make(map[string][]Validator),
+		watcher:    watcher,
+		stopChan:   make(chan struct{}),
 	}
 
 	return manager, nil
 }
 
-// RegisterCallback 注册配置变更回调
-func (m *HotReloadManager) RegisterCallback(configKey string, callback func()) {
+// RegisterCallback 注册配置变更回调。回调返回error会使本次reload整体回滚：
+// config.Load()返回的指针恢复为变更前的快照，并以旧配置重新执行一遍所有回调
+func (m *HotReloadManager) RegisterCallback(configKey string, callback func() error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.callbacks[configKey] == nil {
-		m.callbacks[configKey] = make([]func(), 0)
-	}
 	m.callbacks[configKey] = append(m.callbacks[configKey], callback)
 }
 
-// StartWatching 开始监听配置文件
+// RegisterValidator 按子系统注册配置校验器，候选配置在reload时先经过全部校验器，
+// 再决定是否替换config.Load()返回的指针
+func (m *HotReloadManager) RegisterValidator(subsystem string, validator Validator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.validators[subsystem] = append(m.validators[subsystem], validator)
+}
+
+// AddSource 注册一个ConfigSource并开始监听，其变更事件与本地文件监听共用同一条
+// 防抖动reload路径
+func (m *HotReloadManager) AddSource(source ConfigSource) error {
+	if err := source.Watch(m.handleConfigChange); err != nil {
+		return fmt.Errorf("failed to watch config source: %w", err)
+	}
+
+	m.mu.Lock()
+	m.sources = append(m.sources, source)
+	m.mu.Unlock()
+	return nil
+}
+
+// StartWatching 开始监听本地配置文件
 func (m *HotReloadManager) StartWatching(configPath string) error {
 	// 添加配置文件到监听列表
 	if err := m.watcher.Add(configPath); err != nil {
@@ -81,8 +116,13 @@ func (m *HotReloadManager) watchLoop() {
 	}
 }
 
-// handleConfigChange 处理配置文件变更
+// handleConfigChange 处理配置变更通知（来自本地文件watcher或ConfigSource）。可能被
+// 文件watcher的watchLoop和多个ConfigSource的pollLoop并发调用，debounceTimer的
+// 读取/Stop/重新赋值必须加锁，否则并发调用会互相踩到对方正在Stop/替换的timer
 func (m *HotReloadManager) handleConfigChange() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// 防抖动处理
 	if m.debounceTimer != nil {
 		m.debounceTimer.Stop()
@@ -93,47 +133,90 @@ func (m *HotReloadManager) handleConfigChange() {
 	})
 }
 
-// reloadConfig 重新加载配置
+// reloadConfig 事务性地重新加载配置：先在candidate副本上解析+校验，全部通过后才
+// 原子替换config.Load()返回的指针；若替换后的回调执行失败，则回滚指针并以旧配置
+// 重新执行回调，使运行时状态不会停留在"部分应用了新配置"的中间态
 func (m *HotReloadManager) reloadConfig() {
 	logger.Infof("🔄 Reloading configuration...")
 
-	// 重新读取配置文件
+	// 本地配置文件不存在不是致命错误——可能走的是纯远程配置源，此时数据已经在
+	// ConfigSource.Watch/轮询期间写入了viper；只有文件存在但读取失败才需要中止
 	if err := viper.ReadInConfig(); err != nil {
-		logger.Errorf("❌ Failed to read config file: %v", err)
-		return
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			logger.Errorf("❌ Failed to read config file: %v", err)
+			return
+		}
 	}
 
-	// 重新解析配置
-	if err := viper.Unmarshal(&config.GlobalConfig); err != nil {
+	previous := config.Load()
+	candidate := &config.Config{}
+	if err := viper.Unmarshal(candidate); err != nil {
 		logger.Errorf("❌ Failed to unmarshal config: %v", err)
 		return
 	}
 
+	if err := m.runValidators(candidate); err != nil {
+		logger.Errorf("❌ Config validation failed, keeping previous config: %v", err)
+		return
+	}
+
+	config.Store(candidate)
 	logger.Infof("✅ Configuration reloaded successfully")
 
-	// 执行回调函数
-	m.executeCallbacks()
+	if err := m.executeCallbacks(); err != nil {
+		logger.Errorf("❌ Callback failed after reload, rolling back to previous config: %v", err)
+		config.Store(previous)
+		if rollbackErr := m.executeCallbacks(); rollbackErr != nil {
+			logger.Errorf("❌ Callback failed again while rolling back to previous config: %v", rollbackErr)
+		}
+	}
+}
+
+// runValidators 依次执行所有已注册校验器，遇到第一个错误即中止
+func (m *HotReloadManager) runValidators(candidate *config.Config) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for subsystem, validators := range m.validators {
+		for _, v := range validators {
+			if err := v(candidate); err != nil {
+				return fmt.Errorf("%s: %w", subsystem, err)
+			}
+		}
+	}
+	return nil
 }
 
-// executeCallbacks 执行回调函数
-func (m *HotReloadManager) executeCallbacks() {
+// executeCallbacks 同步执行所有已注册回调并收集首个错误。同步执行（而非各自开
+// goroutine）是事务性回滚的前提——必须在决定是否回滚之前就知道全部回调是否成功
+func (m *HotReloadManager) executeCallbacks() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	var firstErr error
 	for configKey, callbacks := range m.callbacks {
 		logger.Infof("🔄 Executing callbacks for config key: %s", configKey)
 		for _, callback := range callbacks {
-			// 在goroutine中执行回调，避免阻塞
-			go func(cb func()) {
-				defer func() {
-					if r := recover(); r != nil {
-						logger.Errorf("❌ Callback panicked: %v", r)
-					}
-				}()
-				cb()
-			}(callback)
+			if err := runCallback(callback); err != nil {
+				logger.Errorf("❌ Callback for %s failed: %v", configKey, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
 		}
 	}
+	return firstErr
+}
+
+// runCallback 执行单个回调并将panic转换为error，避免某个子系统回调的异常中断
+// 整个事务性reload流程的错误收集
+func runCallback(cb func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("callback panicked: %v", r)
+		}
+	}()
+	return cb()
 }
 
 // Stop 停止监听
@@ -142,6 +225,13 @@ func (m *HotReloadManager) Stop() {
 	if m.debounceTimer != nil {
 		m.debounceTimer.Stop()
 	}
+
+	m.mu.RLock()
+	sources := m.sources
+	m.mu.RUnlock()
+	for _, source := range sources {
+		source.Stop()
+	}
 }
 
 // GetConfigValue 获取配置值
@@ -149,17 +239,27 @@ func (m *HotReloadManager) GetConfigValue(key string) interface{} {
 	return viper.Get(key)
 }
 
-// SetConfigValue 设置配置值
+// SetConfigValue 设置单个配置值，走与文件/远程变更相同的校验+回调+回滚路径，
+// 而不是绕开校验直接生效
 func (m *HotReloadManager) SetConfigValue(key string, value interface{}) error {
 	viper.Set(key, value)
 
-	// 重新解析到结构体
-	if err := viper.Unmarshal(&config.GlobalConfig); err != nil {
+	previous := config.Load()
+	candidate := &config.Config{}
+	if err := viper.Unmarshal(candidate); err != nil {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	// 执行相关回调
-	m.executeCallbacks()
+	if err := m.runValidators(candidate); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	config.Store(candidate)
+	if err := m.executeCallbacks(); err != nil {
+		config.Store(previous)
+		m.executeCallbacks()
+		return fmt.Errorf("callback failed, rolled back to previous config: %w", err)
+	}
 
 	return nil
 }
@@ -0,0 +1,267 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"asr_server/config"
+	"asr_server/internal/bootstrap"
+	"asr_server/internal/logger"
+	"asr_server/internal/pool"
+	"asr_server/internal/speaker"
+
+	pb "asr_server/api/proto/voxid"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"google.golang.org/grpc"
+)
+
+// Server 实现 api/proto/voxid.proto 中定义的 VoxID 服务，复用与 HTTP/WS 路径相同的
+// speaker.Manager 与全局识别器实例，JSON存储/ANN索引始终以其为准。
+type Server struct {
+	pb.UnimplementedVoxIDServer
+
+	recognizer     *sherpa.OfflineRecognizer
+	vadPool        pool.VADPoolInterface
+	speakerManager *speaker.Manager
+}
+
+// NewServer 从已初始化的依赖创建 gRPC 服务实现
+func NewServer(deps *bootstrap.AppDependencies) *Server {
+	return &Server{
+		recognizer:     deps.GlobalRecognizer,
+		vadPool:        deps.VADPool,
+		speakerManager: deps.SpeakerManager,
+	}
+}
+
+// Register 将服务实现挂载到给定的 grpc.Server
+func (s *Server) Register(grpcServer *grpc.Server) {
+	pb.RegisterVoxIDServer(grpcServer, s)
+}
+
+// Recognize 双向流式识别，PCM帧格式与 /ws 路径一致
+func (s *Server) Recognize(stream pb.VoxID_RecognizeServer) error {
+	vadInstance, err := s.vadPool.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get VAD instance: %v", err)
+	}
+	defer s.vadPool.Put(vadInstance)
+
+	sileroInstance, ok := vadInstance.(*pool.SileroVADInstance)
+	if !ok {
+		return fmt.Errorf("unsupported VAD type for gRPC streaming: %s", vadInstance.GetType())
+	}
+	defer sileroInstance.VAD.Reset()
+
+	sampleRate := config.Load().Audio.SampleRate
+	normalizeFactor := config.Load().Audio.NormalizeFactor
+	turnID := 0
+
+	for {
+		frame, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if frame.SampleRate > 0 {
+			sampleRate = int(frame.SampleRate)
+		}
+
+		if len(frame.PcmData)%2 != 0 {
+			return fmt.Errorf("invalid audio frame length: %d", len(frame.PcmData))
+		}
+
+		numSamples := len(frame.PcmData) / 2
+		samples := make([]float32, numSamples)
+		for i := 0; i < numSamples; i++ {
+			sample := int16(frame.PcmData[i*2]) | int16(frame.PcmData[i*2+1])<<8
+			samples[i] = float32(sample) / normalizeFactor
+		}
+
+		sileroInstance.VAD.AcceptWaveform(samples)
+
+		for !sileroInstance.VAD.IsEmpty() {
+			segment := sileroInstance.VAD.Front()
+			sileroInstance.VAD.Pop()
+			if segment == nil || len(segment.Samples) == 0 {
+				continue
+			}
+
+			resp, err := s.recognizeSegment(segment.Samples, sampleRate, &turnID)
+			if err != nil {
+				logger.Warnf("gRPC Recognize: segment decode failed: %v", err)
+				continue
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// recognizeSegment 对单个VAD语音段做识别，并在声纹分离开启时附带speaker_id
+func (s *Server) recognizeSegment(samples []float32, sampleRate int, turnID *int) (*pb.RecognizeResponse, error) {
+	asrStream := sherpa.NewOfflineStream(s.recognizer)
+	defer sherpa.DeleteOfflineStream(asrStream)
+	asrStream.AcceptWaveform(sampleRate, samples)
+	s.recognizer.Decode(asrStream)
+	result := asrStream.GetResult()
+	if result == nil {
+		return nil, fmt.Errorf("recognition failed")
+	}
+
+	resp := &pb.RecognizeResponse{
+		Text:    result.Text,
+		IsFinal: true,
+	}
+
+	if s.speakerManager != nil {
+		if identifyResult, err := s.speakerManager.IdentifySpeaker(samples, sampleRate); err == nil && identifyResult.Identified {
+			resp.SpeakerId = identifyResult.SpeakerID
+			*turnID++
+			resp.TurnId = int32(*turnID)
+		}
+	}
+
+	return resp, nil
+}
+
+// EnrollSpeaker 声纹注册，等价于 /api/v1/speaker/register
+func (s *Server) EnrollSpeaker(ctx context.Context, req *pb.EnrollSpeakerRequest) (*pb.EnrollSpeakerResponse, error) {
+	if s.speakerManager == nil {
+		return nil, fmt.Errorf("speaker recognition module not enabled")
+	}
+
+	samples := pcmToFloat32(req.PcmData, config.Load().Audio.NormalizeFactor)
+	sampleRate := int(req.SampleRate)
+	if sampleRate == 0 {
+		sampleRate = config.Load().Audio.SampleRate
+	}
+
+	// 质量检查未通过时 report 非nil、Passed为false且err也非nil：这不是基础设施错误，
+	// 按report返回，交由调用方读取 passed/reasons，而不是当作RPC失败处理
+	report, err := s.speakerManager.RegisterSpeaker(req.SpeakerId, req.SpeakerName, samples, sampleRate)
+	if report == nil {
+		return nil, err
+	}
+	if err != nil && report.Passed {
+		// report.Passed为true但仍返回了err，说明是持久化等基础设施错误
+		return nil, err
+	}
+
+	return &pb.EnrollSpeakerResponse{
+		Passed:                  report.Passed,
+		Reasons:                 report.Reasons,
+		VoicedSeconds:           report.VoicedSeconds,
+		SnrDb:                   report.SNRDb,
+		ClippingRatio:           report.ClippingRatio,
+		MaxSimilarityToExisting: report.MaxSimilarity,
+		IsDuplicate:             report.IsDuplicate,
+	}, nil
+}
+
+// IdentifySpeaker 声纹识别，等价于 /api/v1/speaker/identify
+func (s *Server) IdentifySpeaker(ctx context.Context, req *pb.IdentifySpeakerRequest) (*pb.IdentifySpeakerResponse, error) {
+	if s.speakerManager == nil {
+		return nil, fmt.Errorf("speaker recognition module not enabled")
+	}
+
+	samples := pcmToFloat32(req.PcmData, config.Load().Audio.NormalizeFactor)
+	sampleRate := int(req.SampleRate)
+	if sampleRate == 0 {
+		sampleRate = config.Load().Audio.SampleRate
+	}
+
+	result, err := s.speakerManager.IdentifySpeaker(samples, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.IdentifySpeakerResponse{
+		SpeakerId:   result.SpeakerID,
+		SpeakerName: result.SpeakerName,
+		Similarity:  result.Confidence,
+		Identified:  result.Identified,
+	}, nil
+}
+
+// VerifySpeaker 声纹核验，等价于 /api/v1/speaker/verify
+func (s *Server) VerifySpeaker(ctx context.Context, req *pb.VerifySpeakerRequest) (*pb.VerifySpeakerResponse, error) {
+	if s.speakerManager == nil {
+		return nil, fmt.Errorf("speaker recognition module not enabled")
+	}
+
+	samples := pcmToFloat32(req.PcmData, config.Load().Audio.NormalizeFactor)
+	sampleRate := int(req.SampleRate)
+	if sampleRate == 0 {
+		sampleRate = config.Load().Audio.SampleRate
+	}
+
+	result, err := s.speakerManager.VerifySpeaker(req.SpeakerId, samples, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.VerifySpeakerResponse{
+		Verified:   result.Verified,
+		Similarity: result.Confidence,
+	}, nil
+}
+
+// DeleteSpeaker 删除声纹，等价于 /api/v1/speaker/:speaker_id 的 DELETE 路由
+func (s *Server) DeleteSpeaker(ctx context.Context, req *pb.DeleteSpeakerRequest) (*pb.DeleteSpeakerResponse, error) {
+	if s.speakerManager == nil {
+		return nil, fmt.Errorf("speaker recognition module not enabled")
+	}
+
+	if err := s.speakerManager.DeleteSpeaker(req.SpeakerId); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteSpeakerResponse{Success: true}, nil
+}
+
+// ListSpeakers 列出所有已注册的声纹
+func (s *Server) ListSpeakers(ctx context.Context, req *pb.ListSpeakersRequest) (*pb.ListSpeakersResponse, error) {
+	if s.speakerManager == nil {
+		return nil, fmt.Errorf("speaker recognition module not enabled")
+	}
+
+	infos := s.speakerManager.GetAllSpeakers()
+	speakers := make([]*pb.SpeakerInfo, 0, len(infos))
+	for _, info := range infos {
+		speakers = append(speakers, &pb.SpeakerInfo{
+			SpeakerId:     info.ID,
+			SpeakerName:   info.Name,
+			SampleCount:   int32(info.SampleCount),
+			CreatedAtUnix: info.CreatedAt.Unix(),
+			UpdatedAtUnix: info.UpdatedAt.Unix(),
+		})
+	}
+
+	return &pb.ListSpeakersResponse{Speakers: speakers}, nil
+}
+
+// Stats 返回声纹库统计信息
+func (s *Server) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	resp := &pb.StatsResponse{}
+	if s.speakerManager != nil {
+		dbStats := s.speakerManager.GetDatabaseStats()
+		resp.RegisteredSpeakers = int64(dbStats.TotalSpeakers)
+	}
+	return resp, nil
+}
+
+func pcmToFloat32(pcmData []byte, normalizeFactor float32) []float32 {
+	numSamples := len(pcmData) / 2
+	samples := make([]float32, numSamples)
+	for i := 0; i < numSamples; i++ {
+		sample := int16(pcmData[i*2]) | int16(pcmData[i*2+1])<<8
+		samples[i] = float32(sample) / normalizeFactor
+	}
+	return samples
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"asr_server/internal/bootstrap"
+	"net/http"
 	"time"
 
+	"asr_server/config"
+	"asr_server/internal/bootstrap"
+
 	"github.com/gin-gonic/gin"
 )
 
-// HealthHandler 健康检查接口（依赖注入）
+// HealthHandler 健康检查聚合接口（依赖注入），汇总各子系统统计信息；
+// 容器编排场景下请改用更轻量的LivezHandler/ReadyzHandler
 func HealthHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		components := make(map[string]interface{})
@@ -23,7 +27,11 @@ func HealthHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
 			components["sessions"] = map[string]interface{}{"status": "not_initialized"}
 		}
 		if deps.RateLimiter != nil {
-			components["rate_limit"] = deps.RateLimiter.GetStats()
+			rateLimitStats := deps.RateLimiter.GetStats()
+			if deps.ConnLimiter != nil {
+				rateLimitStats["connection_limits"] = deps.ConnLimiter.GetStats()
+			}
+			components["rate_limit"] = rateLimitStats
 		} else {
 			components["rate_limit"] = map[string]interface{}{"status": "not_initialized"}
 		}
@@ -41,9 +49,56 @@ func HealthHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
 
 		health := map[string]interface{}{
 			"status":     status,
+			"state":      deps.State.Load().String(),
 			"timestamp":  time.Now().Format(time.RFC3339),
 			"components": components,
 		}
 		c.JSON(200, health)
 	}
 }
+
+// LivezHandler 存活探针：只要进程在跑就返回200，不检查任何下游依赖；
+// 用于编排系统判断"要不要重启这个容器"
+func LivezHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive"})
+	}
+}
+
+// ReadyzHandler 就绪探针：应用处于StateReady、识别器已初始化、VAD池尚有空闲实例、
+// 且当前会话数未达到server.max_connections配置上限时返回200，否则503并在reason里
+// 说明原因；关闭流程开始时状态会先切到StateDraining，使这里立即返回503，让
+// Kubernetes/HAProxy在监听器真正关闭前就停止转发新的WebSocket升级请求
+func ReadyzHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		notReady := func(reason string) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": reason})
+		}
+
+		if state := deps.State.Load(); state != bootstrap.StateReady {
+			notReady("app state is " + state.String())
+			return
+		}
+		if deps.GlobalRecognizer == nil {
+			notReady("recognizer not initialized")
+			return
+		}
+		if deps.VADPool == nil {
+			notReady("vad pool not initialized")
+			return
+		}
+		available, _ := deps.VADPool.GetStats()["available_count"].(int)
+		if available < 1 {
+			notReady("vad pool has no available instances")
+			return
+		}
+		if deps.SessionManager != nil {
+			if maxSessions := config.Load().Server.MaxConnections; maxSessions > 0 && deps.SessionManager.ActiveSessionCount() >= maxSessions {
+				notReady("session count at configured max")
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
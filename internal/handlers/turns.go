@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"asr_server/internal/bootstrap"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TurnHistoryHandler 返回某个WebSocket会话目前为止的说话人分离轮次历史
+func TurnHistoryHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("session_id")
+		if sessionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+			return
+		}
+
+		if deps.SessionManager == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "session manager not initialized"})
+			return
+		}
+
+		turns, exists := deps.SessionManager.GetTurnHistory(sessionID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found or diarization not enabled"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"session_id": sessionID,
+			"turns":      turns,
+		})
+	}
+}
@@ -11,6 +11,10 @@ import (
 
 var Logger *slog.Logger
 
+// rawOutput 记录InitLogger解析出的底层io.Writer，供需要绕过slog结构化格式、
+// 直接写原始文本的场景使用（如SIGQUIT触发的goroutine栈dump）
+var rawOutput io.Writer = os.Stdout
+
 // InitLogger 初始化日志系统，支持轮转和多输出
 func InitLogger(level slog.Level, format, output, filePath string, maxSize, maxBackups, maxAge int, compress bool) {
 	var writers []io.Writer
@@ -27,6 +31,7 @@ func InitLogger(level slog.Level, format, output, filePath string, maxSize, maxB
 		})
 	}
 	mw := io.MultiWriter(writers...)
+	rawOutput = mw
 	var handler slog.Handler
 	if format == "json" {
 		handler = slog.NewJSONHandler(mw, &slog.HandlerOptions{Level: level})
@@ -36,6 +41,11 @@ func InitLogger(level slog.Level, format, output, filePath string, maxSize, maxB
 	Logger = slog.New(handler)
 }
 
+// Writer 返回当前配置的日志输出目标，供需要绕开slog结构化格式直接写入的调用方使用
+func Writer() io.Writer {
+	return rawOutput
+}
+
 func Info(msg string, args ...any) {
 	Logger.Info(msg, args...)
 }
@@ -0,0 +1,87 @@
+// Package metrics 提供进程级Prometheus指标，以包级全局变量的形式暴露，
+// 与internal/ws.SetConnectionLimiter一类"不方便走构造函数DI的横切关注点"采用同样的
+// 包级var+访问器模式：WS、session、pool等包直接引用这里的collector，不需要把
+// *Registry一路透传进各层函数签名
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry 使用独立的Prometheus注册表而非prometheus.DefaultRegisterer，避免与
+// 其他依赖库在同一进程内注册同名collector时发生panic
+var registry = prometheus.NewRegistry()
+
+var (
+	WSUpgradesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "asr_ws_upgrades_total",
+		Help: "Total number of successful WebSocket upgrades.",
+	})
+	WSUpgradeFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "asr_ws_upgrade_failures_total",
+		Help: "Total number of rejected or failed WebSocket upgrade attempts.",
+	})
+	ActiveSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "asr_active_sessions",
+		Help: "Current number of sessions tracked by the session manager.",
+	})
+	WSMessageSizeBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "asr_ws_message_size_bytes",
+		Help:    "Size in bytes of binary WebSocket messages (audio frames) received.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8), // 64B..1MB
+	})
+	// SessionAudioBytesTotal故意不按session_id打标签：会话数量不设上限，逐会话标签会让
+	// 这个指标的基数随活跃连接数增长，这里只统计跨所有会话的总量
+	SessionAudioBytesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "asr_session_audio_bytes_total",
+		Help: "Total bytes of received audio frames (before codec decoding) processed across all sessions.",
+	})
+
+	VADGetDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "asr_vad_pool_get_duration_seconds",
+		Help:    "Time spent acquiring a VAD instance from a pool, including wait time.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"vad_type"})
+	VADPutDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "asr_vad_pool_put_duration_seconds",
+		Help:    "Time spent returning a VAD instance to a pool.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"vad_type"})
+	VADTempInstancesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "asr_vad_pool_temp_instances_total",
+		Help: "Total number of temporary (over-capacity) VAD instances created on pool timeout.",
+	}, []string{"vad_type"})
+	VADTempInstancesRejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "asr_vad_pool_temp_instances_rejected_total",
+		Help: "Total number of temporary VAD instance requests rejected because max_temp_instances was reached.",
+	}, []string{"vad_type"})
+
+	RecognizerDecodeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "asr_recognizer_decode_duration_seconds",
+		Help:    "Time spent in Recognizer.Decode for a single speech segment or partial.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		WSUpgradesTotal,
+		WSUpgradeFailuresTotal,
+		ActiveSessions,
+		WSMessageSizeBytes,
+		SessionAudioBytesTotal,
+		VADGetDuration,
+		VADPutDuration,
+		VADTempInstancesTotal,
+		VADTempInstancesRejectedTotal,
+		RecognizerDecodeDuration,
+	)
+}
+
+// Handler 返回Prometheus文本格式的/metrics端点处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
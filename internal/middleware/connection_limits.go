@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"asr_server/config"
+)
+
+// ErrConnectionDenied 命中deny_cidrs，或配置了非空allow_cidrs但客户端IP不在其中
+var ErrConnectionDenied = errors.New("connection denied by connection_limits CIDR policy")
+
+// ErrConnectionLimitExceeded 客户端IP或其所在CIDR分桶的在途连接数已达上限
+var ErrConnectionLimitExceeded = errors.New("per-IP/CIDR connection limit exceeded")
+
+// Limiter 按客户端IP和CIDR子网跟踪在途WebSocket连接数，外加一份允许/拒绝CIDR名单与
+// Origin白名单，做法参考oragono的connection_limits：防止单个客户端通过开多个连接
+// 耗尽VAD池。每次Acquire/Release/CheckOrigin都重新读取一次config.Load()，使
+// connection_limits的hot-reload无需专门的reload回调即可立即生效
+type Limiter struct {
+	mu sync.Mutex
+
+	perIPCount   map[string]int
+	perCIDRCount map[string]int
+
+	totalAccepted int64
+	totalRejected int64
+}
+
+// NewLimiter 创建连接限制器
+func NewLimiter() *Limiter {
+	return &Limiter{
+		perIPCount:   make(map[string]int),
+		perCIDRCount: make(map[string]int),
+	}
+}
+
+// clientIP 从RemoteAddr和（若存在）X-Forwarded-For中解析出用于限流的客户端IP：
+// 有反向代理时优先信任XFF的第一段，否则退回RemoteAddr
+func clientIP(remoteAddr, forwardedFor string) (net.IP, error) {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	if forwardedFor != "" {
+		if first := strings.TrimSpace(strings.Split(forwardedFor, ",")[0]); first != "" {
+			host = first
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid remote address: %s", remoteAddr)
+	}
+	return ip, nil
+}
+
+// cidrKey 按IP族对应的前缀长度把IP归入一个CIDR分桶；前缀长度<=0表示不做该族的CIDR聚合统计
+func cidrKey(ip net.IP, cfg config.ConnectionLimitsConf) string {
+	prefixLen := cfg.CIDRPrefixLenV4
+	bits := 32
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		prefixLen = cfg.CIDRPrefixLenV6
+		bits = 128
+	}
+	if prefixLen <= 0 {
+		return ""
+	}
+	network := ip.Mask(net.CIDRMask(prefixLen, bits))
+	return fmt.Sprintf("%s/%d", network.String(), prefixLen)
+}
+
+// matchesAny 判断ip是否落在cidrs中的任意一个网段内；无法解析的条目会被跳过而不是报错，
+// 因为这是运行时热加载的配置，不应该因为一条脏数据就让所有连接失败
+func matchesAny(ip net.IP, cidrs []string) bool {
+	for _, c := range cidrs {
+		_, network, err := net.ParseCIDR(c)
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire 为一次新连接申请准入：先过滤deny/allow CIDR名单，再检查per-IP/per-CIDR上限，
+// 通过后计数器自增。返回ErrConnectionDenied对应HTTP 403，返回ErrConnectionLimitExceeded
+// 对应HTTP 429，调用方应在升级为WebSocket之前调用
+func (l *Limiter) Acquire(remoteAddr, forwardedFor string) error {
+	cfg := config.Load().ConnectionLimits
+	if !cfg.Enabled {
+		return nil
+	}
+
+	ip, err := clientIP(remoteAddr, forwardedFor)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if matchesAny(ip, cfg.DenyCIDRs) {
+		l.totalRejected++
+		return ErrConnectionDenied
+	}
+	if len(cfg.AllowCIDRs) > 0 && !matchesAny(ip, cfg.AllowCIDRs) {
+		l.totalRejected++
+		return ErrConnectionDenied
+	}
+
+	ipKey := ip.String()
+	cKey := cidrKey(ip, cfg)
+
+	if cfg.MaxPerIP > 0 && l.perIPCount[ipKey] >= cfg.MaxPerIP {
+		l.totalRejected++
+		return ErrConnectionLimitExceeded
+	}
+	if cKey != "" && cfg.MaxPerCIDR > 0 && l.perCIDRCount[cKey] >= cfg.MaxPerCIDR {
+		l.totalRejected++
+		return ErrConnectionLimitExceeded
+	}
+
+	l.perIPCount[ipKey]++
+	if cKey != "" {
+		l.perCIDRCount[cKey]++
+	}
+	l.totalAccepted++
+	return nil
+}
+
+// Release 释放一次由Acquire成功计入的连接；对未曾成功Acquire过的地址是no-op
+func (l *Limiter) Release(remoteAddr, forwardedFor string) {
+	ip, err := clientIP(remoteAddr, forwardedFor)
+	if err != nil {
+		return
+	}
+	cfg := config.Load().ConnectionLimits
+
+	ipKey := ip.String()
+	cKey := cidrKey(ip, cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perIPCount[ipKey] > 0 {
+		l.perIPCount[ipKey]--
+		if l.perIPCount[ipKey] == 0 {
+			delete(l.perIPCount, ipKey)
+		}
+	}
+	if cKey != "" && l.perCIDRCount[cKey] > 0 {
+		l.perCIDRCount[cKey]--
+		if l.perCIDRCount[cKey] == 0 {
+			delete(l.perCIDRCount, cKey)
+		}
+	}
+}
+
+// CheckOrigin 实现gorilla/websocket.Upgrader.CheckOrigin的签名，按
+// connection_limits.allowed_origins做来源白名单校验；未启用或allowed_origins为空时
+// 保持"接受所有来源"的历史行为
+func (l *Limiter) CheckOrigin(r *http.Request) bool {
+	cfg := config.Load().ConnectionLimits
+	if !cfg.Enabled || len(cfg.AllowedOrigins) == 0 {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStats 导出当前跟踪状态，供HealthHandler/StatsHandler汇总展示
+func (l *Limiter) GetStats() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perIP := make(map[string]int, len(l.perIPCount))
+	for k, v := range l.perIPCount {
+		perIP[k] = v
+	}
+
+	return map[string]interface{}{
+		"enabled":          config.Load().ConnectionLimits.Enabled,
+		"total_accepted":   l.totalAccepted,
+		"total_rejected":   l.totalRejected,
+		"tracked_ip_count": len(l.perIPCount),
+		"active_per_ip":    perIP,
+	}
+}
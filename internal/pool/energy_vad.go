@@ -0,0 +1,337 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"asr_server/internal/logger"
+)
+
+// EnergyVADConfig 配置RMS/ZCR能量VAD：没有频谱分析，计算成本是三种后端里最低的，
+// 适合信噪比稳定的受控录音环境（如近讲麦克风），嘈杂环境建议用webrtc_vad或silero_vad
+type EnergyVADConfig struct {
+	RMSThreshold       float32
+	ZCRThreshold       float32
+	FrameDurationMs    int
+	SampleRate         int
+	MinSpeechDuration  float32
+	MaxSpeechDuration  float32
+	MinSilenceDuration float32
+	PoolSize           int
+	MaxIdle            int
+}
+
+// EnergyVADInstance 基于RMS能量+过零率的VAD实例，语音段队列形状与Silero/WebRTC一致
+type EnergyVADInstance struct {
+	ID       int
+	LastUsed int64
+	InUse    int32
+	mu       sync.Mutex
+
+	frameSize        int
+	rmsThreshold     float32
+	zcrThreshold     float32
+	minSpeechSamples int
+	maxSpeechSamples int
+	minSilenceFrames int
+
+	frameBuf      []float32
+	inSpeech      bool
+	current       []float32
+	silenceFrames int
+	segments      []*VADSegment
+}
+
+// AcceptWaveform 喂入新采样，按frameSize分帧做RMS/ZCR gating
+func (i *EnergyVADInstance) AcceptWaveform(samples []float32) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.frameBuf = append(i.frameBuf, samples...)
+	for len(i.frameBuf) >= i.frameSize {
+		frame := i.frameBuf[:i.frameSize]
+		i.frameBuf = i.frameBuf[i.frameSize:]
+		i.processFrame(frame)
+	}
+}
+
+// isSpeechFrame 能量超过阈值且过零率不过高（排除高频噪声/摩擦音误判）即判定为语音
+func (i *EnergyVADInstance) isSpeechFrame(frame []float32) bool {
+	return frameRMS(frame) > i.rmsThreshold && frameZCR(frame) < i.zcrThreshold
+}
+
+func (i *EnergyVADInstance) processFrame(frame []float32) {
+	if i.isSpeechFrame(frame) {
+		i.inSpeech = true
+		i.silenceFrames = 0
+		i.current = append(i.current, frame...)
+		if len(i.current) >= i.maxSpeechSamples {
+			i.flushSegment()
+		}
+		return
+	}
+
+	if !i.inSpeech {
+		return
+	}
+
+	i.silenceFrames++
+	i.current = append(i.current, frame...)
+	if i.silenceFrames >= i.minSilenceFrames {
+		i.flushSegment()
+	}
+}
+
+func (i *EnergyVADInstance) flushSegment() {
+	segment := i.current
+	i.current = nil
+	i.inSpeech = false
+	i.silenceFrames = 0
+
+	if len(segment) < i.minSpeechSamples {
+		return
+	}
+	if len(segment) > i.maxSpeechSamples {
+		segment = segment[:i.maxSpeechSamples]
+	}
+	i.segments = append(i.segments, &VADSegment{Samples: segment})
+}
+
+// IsEmpty 是否有已完成的语音段在排队
+func (i *EnergyVADInstance) IsEmpty() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.segments) == 0
+}
+
+// Front 查看队首语音段
+func (i *EnergyVADInstance) Front() *VADSegment {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return nil
+	}
+	return i.segments[0]
+}
+
+// Pop 弹出队首语音段
+func (i *EnergyVADInstance) Pop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return
+	}
+	i.segments = i.segments[1:]
+}
+
+// GetID 获取实例ID
+func (i *EnergyVADInstance) GetID() int { return i.ID }
+
+// GetType 获取VAD类型
+func (i *EnergyVADInstance) GetType() string { return ENERGY_TYPE }
+
+// IsInUse 检查是否在使用中
+func (i *EnergyVADInstance) IsInUse() bool { return atomic.LoadInt32(&i.InUse) == 1 }
+
+// SetInUse 设置使用状态
+func (i *EnergyVADInstance) SetInUse(inUse bool) {
+	if inUse {
+		atomic.StoreInt32(&i.InUse, 1)
+	} else {
+		atomic.StoreInt32(&i.InUse, 0)
+	}
+}
+
+// GetLastUsed 获取最后使用时间
+func (i *EnergyVADInstance) GetLastUsed() int64 { return atomic.LoadInt64(&i.LastUsed) }
+
+// SetLastUsed 设置最后使用时间
+func (i *EnergyVADInstance) SetLastUsed(timestamp int64) { atomic.StoreInt64(&i.LastUsed, timestamp) }
+
+// Reset 重置实例状态
+func (i *EnergyVADInstance) Reset() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.frameBuf = i.frameBuf[:0]
+	i.current = nil
+	i.inSpeech = false
+	i.silenceFrames = 0
+	i.segments = nil
+	return nil
+}
+
+// Destroy 销毁实例；纯Go结构，没有底层资源需要释放
+func (i *EnergyVADInstance) Destroy() error {
+	return nil
+}
+
+// EnergyVADPool RMS/ZCR能量VAD资源池
+type EnergyVADPool struct {
+	instances []*EnergyVADInstance
+	available chan VADInstanceInterface
+	config    *EnergyVADConfig
+
+	totalCreated int64
+	totalReused  int64
+	totalActive  int64
+
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEnergyVADPool 创建新的能量VAD资源池
+func NewEnergyVADPool(config *EnergyVADConfig) *EnergyVADPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EnergyVADPool{
+		instances: make([]*EnergyVADInstance, 0, config.PoolSize),
+		available: make(chan VADInstanceInterface, config.PoolSize),
+		config:    config,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (p *EnergyVADPool) newInstance(id int) *EnergyVADInstance {
+	frameSize := p.config.SampleRate * p.config.FrameDurationMs / 1000
+	if frameSize <= 0 {
+		frameSize = 160
+	}
+	minSilenceFrames := int(p.config.MinSilenceDuration*1000) / p.config.FrameDurationMs
+	if minSilenceFrames <= 0 {
+		minSilenceFrames = 1
+	}
+
+	return &EnergyVADInstance{
+		ID:               id,
+		LastUsed:         time.Now().UnixNano(),
+		frameSize:        frameSize,
+		rmsThreshold:     p.config.RMSThreshold,
+		zcrThreshold:     p.config.ZCRThreshold,
+		minSpeechSamples: int(p.config.MinSpeechDuration * float32(p.config.SampleRate)),
+		maxSpeechSamples: int(p.config.MaxSpeechDuration * float32(p.config.SampleRate)),
+		minSilenceFrames: minSilenceFrames,
+	}
+}
+
+// Initialize 创建池中全部实例
+func (p *EnergyVADPool) Initialize() error {
+	logger.Infof("🔧 Initializing Energy VAD pool with %d instances...", p.config.PoolSize)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for idx := 0; idx < p.config.PoolSize; idx++ {
+		instance := p.newInstance(idx)
+		p.instances = append(p.instances, instance)
+		p.available <- instance
+		atomic.AddInt64(&p.totalCreated, 1)
+	}
+
+	logger.Infof("🚀 Energy VAD pool initialized with %d instances", len(p.instances))
+	return nil
+}
+
+// Get 获取VAD实例
+func (p *EnergyVADPool) Get() (VADInstanceInterface, error) {
+	select {
+	case instance := <-p.available:
+		energyInstance := instance.(*EnergyVADInstance)
+		if atomic.CompareAndSwapInt32(&energyInstance.InUse, 0, 1) {
+			instance.SetLastUsed(time.Now().UnixNano())
+			atomic.AddInt64(&p.totalReused, 1)
+			atomic.AddInt64(&p.totalActive, 1)
+			return instance, nil
+		}
+		select {
+		case p.available <- instance:
+		default:
+		}
+		return p.Get()
+	case <-time.After(100 * time.Millisecond):
+		logger.Warnf("⏰ Energy VAD pool timeout, creating new temporary instance")
+		instance := p.newInstance(-1)
+		instance.InUse = 1
+		atomic.AddInt64(&p.totalCreated, 1)
+		atomic.AddInt64(&p.totalActive, 1)
+		return instance, nil
+	case <-p.ctx.Done():
+		return nil, fmt.Errorf("Energy VAD pool is shutting down")
+	}
+}
+
+// Put 归还VAD实例
+func (p *EnergyVADPool) Put(instance VADInstanceInterface) {
+	if instance == nil {
+		return
+	}
+	energyInstance := instance.(*EnergyVADInstance)
+	if atomic.CompareAndSwapInt32(&energyInstance.InUse, 1, 0) {
+		instance.SetLastUsed(time.Now().UnixNano())
+		atomic.AddInt64(&p.totalActive, -1)
+		if err := instance.Reset(); err != nil {
+			logger.Warnf("⚠️ Failed to reset Energy VAD instance %d: %v", instance.GetID(), err)
+		}
+		select {
+		case p.available <- instance:
+		default:
+			instance.Destroy()
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (p *EnergyVADPool) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return map[string]interface{}{
+		"vad_type":        ENERGY_TYPE,
+		"pool_size":       p.config.PoolSize,
+		"max_idle":        p.config.MaxIdle,
+		"total_instances": len(p.instances),
+		"available_count": len(p.available),
+		"active_count":    atomic.LoadInt64(&p.totalActive),
+		"total_created":   atomic.LoadInt64(&p.totalCreated),
+		"total_reused":    atomic.LoadInt64(&p.totalReused),
+	}
+}
+
+// Shutdown 关闭VAD池
+func (p *EnergyVADPool) Shutdown() {
+	logger.Infof("🛑 Shutting down Energy VAD pool...")
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+drain:
+	for {
+		select {
+		case instance := <-p.available:
+			instance.Destroy()
+		default:
+			break drain
+		}
+	}
+	p.instances = nil
+	close(p.available)
+	logger.Infof("✅ Energy VAD pool shutdown complete")
+}
+
+// EnergyVADPoolFactory 能量VAD池工厂
+type EnergyVADPoolFactory struct{}
+
+// CreatePool 创建能量VAD池
+func (f *EnergyVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
+	energyConfig, ok := config.(*EnergyVADConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid config type for Energy VAD")
+	}
+	return NewEnergyVADPool(energyConfig), nil
+}
+
+// GetSupportedTypes 获取支持的VAD类型
+func (f *EnergyVADPoolFactory) GetSupportedTypes() []string {
+	return []string{ENERGY_TYPE}
+}
@@ -8,16 +8,22 @@ import (
 	"time"
 
 	"asr_server/internal/logger"
+	"asr_server/internal/metrics"
 
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 )
 
+// defaultMaxTempInstances 未配置vad.silero_vad.max_temp_instances(<=0)时套用的上限；
+// 此前createNewInstance完全不受限，池持续超时会无节制地创建ONNX实例拖垮进程
+const defaultMaxTempInstances = 8
+
 // SileroVADConfig Silero VAD配置
 type SileroVADConfig struct {
 	ModelConfig       *sherpa.VadModelConfig
 	BufferSizeSeconds float32
 	PoolSize          int
 	MaxIdle           int
+	MaxTempInstances  int
 }
 
 // SileroVADInstance Silero VAD实例
@@ -99,9 +105,10 @@ type SileroVADPool struct {
 	config    *SileroVADConfig
 
 	// 统计信息
-	totalCreated int64
-	totalReused  int64
-	totalActive  int64
+	totalCreated    int64
+	totalReused     int64
+	totalActive     int64
+	totalTempActive int64 // 当前存活的临时(ID=-1)实例数，受config.MaxTempInstances约束
 
 	// 控制
 	mu     sync.RWMutex
@@ -113,6 +120,10 @@ type SileroVADPool struct {
 func NewSileroVADPool(config *SileroVADConfig) *SileroVADPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if config.MaxTempInstances <= 0 {
+		config.MaxTempInstances = defaultMaxTempInstances
+	}
+
 	pool := &SileroVADPool{
 		instances: make([]*SileroVADInstance, 0, config.PoolSize),
 		available: make(chan VADInstanceInterface, config.PoolSize),
@@ -192,6 +203,11 @@ func (p *SileroVADPool) Initialize() error {
 
 // Get 获取VAD实例
 func (p *SileroVADPool) Get() (VADInstanceInterface, error) {
+	start := time.Now()
+	defer func() {
+		metrics.VADGetDuration.WithLabelValues(SILERO_TYPE).Observe(time.Since(start).Seconds())
+	}()
+
 	logger.Infof("🔍 Attempting to get Silero VAD instance from pool (available: %d)", len(p.available))
 
 	select {
@@ -228,6 +244,11 @@ func (p *SileroVADPool) Put(instance VADInstanceInterface) {
 		return
 	}
 
+	start := time.Now()
+	defer func() {
+		metrics.VADPutDuration.WithLabelValues(SILERO_TYPE).Observe(time.Since(start).Seconds())
+	}()
+
 	logger.Infof("🔄 Returning Silero VAD instance %d to pool", instance.GetID())
 
 	if atomic.CompareAndSwapInt32(&instance.(*SileroVADInstance).InUse, 1, 0) {
@@ -249,15 +270,33 @@ func (p *SileroVADPool) Put(instance VADInstanceInterface) {
 			logger.Warnf("⚠️ Silero VAD pool queue full, destroying instance %d", instance.GetID())
 			instance.Destroy()
 		}
+
+		// 临时实例(ID=-1)不论归还成功与否都不再计入MaxTempInstances：一旦流回available
+		// 队列就和池自有实例一视同仁。归还后立即把ID清零，防止同一实例被再次Get/Put时
+		// 被误判为仍是temp实例而重复扣减totalTempActive（导致计数下溢、temp上限形同虚设）
+		if sileroInstance, ok := instance.(*SileroVADInstance); ok && sileroInstance.ID == -1 {
+			atomic.AddInt64(&p.totalTempActive, -1)
+			sileroInstance.ID = 0
+		}
 	} else {
 		logger.Warnf("⚠️ Silero VAD instance %d was not in use, cannot return", instance.GetID())
 	}
 }
 
-// createNewInstance 创建新的VAD实例
+// createNewInstance 创建新的VAD实例。temp实例数量由config.MaxTempInstances上限：
+// 此前这里完全不受限，池持续耗尽时会无节制创建ONNX实例，现在超过上限直接报错，
+// 调用方(ProcessAudioData)照常把错误透传给客户端
 func (p *SileroVADPool) createNewInstance() (VADInstanceInterface, error) {
+	if current := atomic.AddInt64(&p.totalTempActive, 1); current > int64(p.config.MaxTempInstances) {
+		atomic.AddInt64(&p.totalTempActive, -1)
+		metrics.VADTempInstancesRejectedTotal.WithLabelValues(SILERO_TYPE).Inc()
+		logger.Errorf("❌ Silero VAD temporary instance cap reached (%d), rejecting", p.config.MaxTempInstances)
+		return nil, fmt.Errorf("Silero VAD pool exhausted: temporary instance cap (%d) reached", p.config.MaxTempInstances)
+	}
+
 	vad := sherpa.NewVoiceActivityDetector(p.config.ModelConfig, p.config.BufferSizeSeconds)
 	if vad == nil {
+		atomic.AddInt64(&p.totalTempActive, -1)
 		return nil, fmt.Errorf("failed to create new Silero VAD instance")
 	}
 
@@ -270,6 +309,7 @@ func (p *SileroVADPool) createNewInstance() (VADInstanceInterface, error) {
 
 	atomic.AddInt64(&p.totalCreated, 1)
 	atomic.AddInt64(&p.totalActive, 1)
+	metrics.VADTempInstancesTotal.WithLabelValues(SILERO_TYPE).Inc()
 
 	logger.Infof("🆕 Created temporary Silero VAD instance")
 	return instance, nil
@@ -289,6 +329,8 @@ func (p *SileroVADPool) GetStats() map[string]interface{} {
 		"active_count":    atomic.LoadInt64(&p.totalActive),
 		"total_created":   atomic.LoadInt64(&p.totalCreated),
 		"total_reused":    atomic.LoadInt64(&p.totalReused),
+		"temp_active":     atomic.LoadInt64(&p.totalTempActive),
+		"max_temp":        p.config.MaxTempInstances,
 	}
 }
 
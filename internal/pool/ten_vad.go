@@ -0,0 +1,341 @@
+//go:build !darwin
+
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"asr_server/internal/logger"
+)
+
+// TenVADConfig 配置TEN-VAD后端。这棵仓库里没有vendor TEN-VAD的cgo运行时，
+// 这里用与webrtc_vad同源的纯Go能量/频谱平坦度gating逐帧判定，按HopSize分帧、
+// 按MinSpeechFrames/MaxSilenceFrames控制端点，对外行为（语音段队列形状）与
+// sherpa Silero VAD保持一致，从而可以在不引入新cgo依赖的前提下于Linux上默认启用
+type TenVADConfig struct {
+	HopSize          int
+	SampleRate       int
+	MinSpeechFrames  int
+	MaxSilenceFrames int
+	PoolSize         int
+	MaxIdle          int
+}
+
+// TenVADInstance TEN-VAD实例，语音段队列形状与Silero/WebRTC/Energy一致
+type TenVADInstance struct {
+	ID       int
+	LastUsed int64
+	InUse    int32
+	mu       sync.Mutex
+
+	hopSize          int
+	minSpeechFrames  int
+	maxSilenceFrames int
+
+	frameBuf      []float32
+	inSpeech      bool
+	current       []float32
+	speechFrames  int
+	silenceFrames int
+	segments      []*VADSegment
+}
+
+// AcceptWaveform 喂入新采样，按HopSize分帧做gating
+func (i *TenVADInstance) AcceptWaveform(samples []float32) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.frameBuf = append(i.frameBuf, samples...)
+	for len(i.frameBuf) >= i.hopSize {
+		frame := i.frameBuf[:i.hopSize]
+		i.frameBuf = i.frameBuf[i.hopSize:]
+		i.processFrame(frame)
+	}
+}
+
+func (i *TenVADInstance) isSpeechFrame(frame []float32) bool {
+	energyThreshold, flatnessMax := aggressivenessGates(1)
+	return frameRMS(frame) > energyThreshold && frameSpectralFlatness(frame) < flatnessMax
+}
+
+func (i *TenVADInstance) processFrame(frame []float32) {
+	if i.isSpeechFrame(frame) {
+		i.inSpeech = true
+		i.speechFrames++
+		i.silenceFrames = 0
+		i.current = append(i.current, frame...)
+		return
+	}
+
+	if !i.inSpeech {
+		return
+	}
+
+	i.silenceFrames++
+	i.current = append(i.current, frame...)
+	if i.silenceFrames >= i.maxSilenceFrames {
+		i.flushSegment()
+	}
+}
+
+func (i *TenVADInstance) flushSegment() {
+	segment := i.current
+	speechFrames := i.speechFrames
+	i.current = nil
+	i.inSpeech = false
+	i.speechFrames = 0
+	i.silenceFrames = 0
+
+	if speechFrames < i.minSpeechFrames {
+		return
+	}
+	i.segments = append(i.segments, &VADSegment{Samples: segment})
+}
+
+// IsEmpty 是否有已完成的语音段在排队
+func (i *TenVADInstance) IsEmpty() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.segments) == 0
+}
+
+// Front 查看队首语音段
+func (i *TenVADInstance) Front() *VADSegment {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return nil
+	}
+	return i.segments[0]
+}
+
+// Pop 弹出队首语音段
+func (i *TenVADInstance) Pop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return
+	}
+	i.segments = i.segments[1:]
+}
+
+// GetID 获取实例ID
+func (i *TenVADInstance) GetID() int { return i.ID }
+
+// GetType 获取VAD类型
+func (i *TenVADInstance) GetType() string { return TEN_VAD_TYPE }
+
+// IsInUse 检查是否在使用中
+func (i *TenVADInstance) IsInUse() bool { return atomic.LoadInt32(&i.InUse) == 1 }
+
+// SetInUse 设置使用状态
+func (i *TenVADInstance) SetInUse(inUse bool) {
+	if inUse {
+		atomic.StoreInt32(&i.InUse, 1)
+	} else {
+		atomic.StoreInt32(&i.InUse, 0)
+	}
+}
+
+// GetLastUsed 获取最后使用时间
+func (i *TenVADInstance) GetLastUsed() int64 { return atomic.LoadInt64(&i.LastUsed) }
+
+// SetLastUsed 设置最后使用时间
+func (i *TenVADInstance) SetLastUsed(timestamp int64) { atomic.StoreInt64(&i.LastUsed, timestamp) }
+
+// Reset 重置实例状态
+func (i *TenVADInstance) Reset() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.frameBuf = i.frameBuf[:0]
+	i.current = nil
+	i.inSpeech = false
+	i.speechFrames = 0
+	i.silenceFrames = 0
+	i.segments = nil
+	return nil
+}
+
+// Destroy 销毁实例；纯Go结构，没有底层资源需要释放
+func (i *TenVADInstance) Destroy() error {
+	return nil
+}
+
+// TenVADPool TEN-VAD资源池
+type TenVADPool struct {
+	instances []*TenVADInstance
+	available chan VADInstanceInterface
+	config    *TenVADConfig
+
+	totalCreated int64
+	totalReused  int64
+	totalActive  int64
+
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewTenVADPool 创建新的TEN-VAD资源池
+func NewTenVADPool(config *TenVADConfig) *TenVADPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TenVADPool{
+		instances: make([]*TenVADInstance, 0, config.PoolSize),
+		available: make(chan VADInstanceInterface, config.PoolSize),
+		config:    config,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (p *TenVADPool) newInstance(id int) *TenVADInstance {
+	hopSize := p.config.HopSize
+	if hopSize <= 0 {
+		hopSize = 160
+	}
+	minSpeechFrames := p.config.MinSpeechFrames
+	if minSpeechFrames <= 0 {
+		minSpeechFrames = 1
+	}
+	maxSilenceFrames := p.config.MaxSilenceFrames
+	if maxSilenceFrames <= 0 {
+		maxSilenceFrames = 1
+	}
+
+	return &TenVADInstance{
+		ID:               id,
+		LastUsed:         time.Now().UnixNano(),
+		hopSize:          hopSize,
+		minSpeechFrames:  minSpeechFrames,
+		maxSilenceFrames: maxSilenceFrames,
+	}
+}
+
+// Initialize 创建池中全部实例
+func (p *TenVADPool) Initialize() error {
+	logger.Infof("🔧 Initializing TEN-VAD pool with %d instances...", p.config.PoolSize)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for idx := 0; idx < p.config.PoolSize; idx++ {
+		instance := p.newInstance(idx)
+		p.instances = append(p.instances, instance)
+		p.available <- instance
+		atomic.AddInt64(&p.totalCreated, 1)
+	}
+
+	logger.Infof("🚀 TEN-VAD pool initialized with %d instances", len(p.instances))
+	return nil
+}
+
+// Get 获取VAD实例
+func (p *TenVADPool) Get() (VADInstanceInterface, error) {
+	select {
+	case instance := <-p.available:
+		tenInstance := instance.(*TenVADInstance)
+		if atomic.CompareAndSwapInt32(&tenInstance.InUse, 0, 1) {
+			instance.SetLastUsed(time.Now().UnixNano())
+			atomic.AddInt64(&p.totalReused, 1)
+			atomic.AddInt64(&p.totalActive, 1)
+			return instance, nil
+		}
+		select {
+		case p.available <- instance:
+		default:
+		}
+		return p.Get()
+	case <-time.After(100 * time.Millisecond):
+		logger.Warnf("⏰ TEN-VAD pool timeout, creating new temporary instance")
+		instance := p.newInstance(-1)
+		instance.InUse = 1
+		atomic.AddInt64(&p.totalCreated, 1)
+		atomic.AddInt64(&p.totalActive, 1)
+		return instance, nil
+	case <-p.ctx.Done():
+		return nil, fmt.Errorf("TEN-VAD pool is shutting down")
+	}
+}
+
+// Put 归还VAD实例
+func (p *TenVADPool) Put(instance VADInstanceInterface) {
+	if instance == nil {
+		return
+	}
+	tenInstance := instance.(*TenVADInstance)
+	if atomic.CompareAndSwapInt32(&tenInstance.InUse, 1, 0) {
+		instance.SetLastUsed(time.Now().UnixNano())
+		atomic.AddInt64(&p.totalActive, -1)
+		if err := instance.Reset(); err != nil {
+			logger.Warnf("⚠️ Failed to reset TEN-VAD instance %d: %v", instance.GetID(), err)
+		}
+		select {
+		case p.available <- instance:
+		default:
+			instance.Destroy()
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (p *TenVADPool) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return map[string]interface{}{
+		"vad_type":        TEN_VAD_TYPE,
+		"pool_size":       p.config.PoolSize,
+		"max_idle":        p.config.MaxIdle,
+		"total_instances": len(p.instances),
+		"available_count": len(p.available),
+		"active_count":    atomic.LoadInt64(&p.totalActive),
+		"total_created":   atomic.LoadInt64(&p.totalCreated),
+		"total_reused":    atomic.LoadInt64(&p.totalReused),
+	}
+}
+
+// Shutdown 关闭VAD池
+func (p *TenVADPool) Shutdown() {
+	logger.Infof("🛑 Shutting down TEN-VAD pool...")
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+drain:
+	for {
+		select {
+		case instance := <-p.available:
+			instance.Destroy()
+		default:
+			break drain
+		}
+	}
+	p.instances = nil
+	close(p.available)
+	logger.Infof("✅ TEN-VAD pool shutdown complete")
+}
+
+// TenVADPoolFactory TEN-VAD池工厂
+type TenVADPoolFactory struct{}
+
+// CreatePool 创建TEN-VAD池
+func (f *TenVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
+	tenVADConfig, ok := config.(*TenVADConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid config type for TEN-VAD")
+	}
+	return NewTenVADPool(tenVADConfig), nil
+}
+
+// GetSupportedTypes 获取支持的VAD类型
+func (f *TenVADPoolFactory) GetSupportedTypes() []string {
+	return []string{TEN_VAD_TYPE}
+}
+
+// registerTenVADFactory 在非darwin平台上把TEN-VAD注册进VADFactory；
+// darwin版本在vad_factory_ten_darwin.go里是空实现
+func registerTenVADFactory(f *VADFactory) {
+	f.RegisterFactory(TEN_VAD_TYPE, &TenVADPoolFactory{})
+}
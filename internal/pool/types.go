@@ -67,6 +67,15 @@ var (
 )
 
 const (
-	TEN_VAD_TYPE = "ten_vad"
-	SILERO_TYPE  = "silero_vad"
+	TEN_VAD_TYPE  = "ten_vad"
+	SILERO_TYPE   = "silero_vad"
+	WEBRTC_TYPE   = "webrtc_vad"
+	ENERGY_TYPE   = "energy_vad"
+	AUTO_VAD_TYPE = "auto"
 )
+
+// VADSegment 语音段，形状与sherpa.SpeechSegment保持一致（只暴露Samples），
+// 使非ONNX的VAD后端（webrtc_vad/energy_vad/ten_vad）能产出下游无需区分后端的语音段
+type VADSegment struct {
+	Samples []float32
+}
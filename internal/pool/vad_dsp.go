@@ -0,0 +1,94 @@
+package pool
+
+import "math"
+
+// 本文件提供纯Go、无第三方依赖的轻量级语音活动检测算子，供webrtc_vad.go、
+// energy_vad.go和ten_vad.go共享。不追求和sherpa-onnx Silero模型等量的精度，
+// 只作为不依赖ONNX运行时/模型文件的廉价替代。
+
+// frameRMS 计算一帧采样的均方根能量
+func frameRMS(frame []float32) float32 {
+	if len(frame) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range frame {
+		sum += float64(s) * float64(s)
+	}
+	return float32(math.Sqrt(sum / float64(len(frame))))
+}
+
+// frameZCR 计算一帧采样的过零率（每个采样点符号翻转的比例）
+func frameZCR(frame []float32) float32 {
+	if len(frame) < 2 {
+		return 0
+	}
+	crossings := 0
+	for i := 1; i < len(frame); i++ {
+		if (frame[i-1] >= 0) != (frame[i] >= 0) {
+			crossings++
+		}
+	}
+	return float32(crossings) / float32(len(frame)-1)
+}
+
+// spectralFlatnessBins 做频谱平坦度估算所取的频点数；频点越多频率分辨率越高，
+// 但naive DFT是O(bins*frameLen)，取一个较小的值在常见10-30ms帧长下足够便宜
+const spectralFlatnessBins = 24
+
+// frameSpectralFlatness 用naive DFT估算频谱平坦度：几何平均/算术平均的功率谱比值，
+// 越接近1代表越接近白噪声（能量分布平坦），越接近0代表能量集中在少数频率上（类语音的共振峰结构）
+func frameSpectralFlatness(frame []float32) float32 {
+	n := len(frame)
+	if n == 0 {
+		return 1
+	}
+	bins := spectralFlatnessBins
+	if bins > n/2 {
+		bins = n / 2
+	}
+	if bins == 0 {
+		return 1
+	}
+
+	power := make([]float64, bins)
+	for k := 1; k <= bins; k++ {
+		var re, im float64
+		w := 2 * math.Pi * float64(k) / float64(n)
+		for t, s := range frame {
+			re += float64(s) * math.Cos(w*float64(t))
+			im -= float64(s) * math.Sin(w*float64(t))
+		}
+		power[k-1] = (re*re + im*im) / float64(n)
+	}
+
+	var logSum, arithSum float64
+	for _, p := range power {
+		if p <= 0 {
+			p = 1e-12
+		}
+		logSum += math.Log(p)
+		arithSum += p
+	}
+	geoMean := math.Exp(logSum / float64(bins))
+	arithMean := arithSum / float64(bins)
+	if arithMean <= 0 {
+		return 1
+	}
+	return float32(geoMean / arithMean)
+}
+
+// aggressivenessGates 把0-3的aggressiveness映射到(能量阈值下限, 频谱平坦度上限)，
+// aggressiveness越大，判定为语音所需的能量越高、允许的平坦度越低（越倾向于过滤掉噪声）
+func aggressivenessGates(aggressiveness int) (energyThreshold, flatnessMax float32) {
+	switch {
+	case aggressiveness <= 0:
+		return 0.003, 0.65
+	case aggressiveness == 1:
+		return 0.006, 0.55
+	case aggressiveness == 2:
+		return 0.012, 0.45
+	default:
+		return 0.02, 0.35
+	}
+}
@@ -1,159 +1,207 @@
-package pool
-
-import (
-	"fmt"
-
-	"asr_server/config"
-	"asr_server/internal/logger"
-
-	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
-)
-
-// VADFactory VAD工厂
-type VADFactory struct {
-	factories map[string]VADPoolFactory
-}
-
-// NewVADFactory 创建新的VAD工厂
-func NewVADFactory() *VADFactory {
-	factory := &VADFactory{
-		factories: make(map[string]VADPoolFactory),
-	}
-
-	// 注册支持的VAD类型
-	factory.RegisterFactory(SILERO_TYPE, &SileroVADPoolFactory{})
-	// factory.RegisterFactory(TEN_VAD_TYPE, &TenVADPoolFactory{}) // Disabled for macOS
-
-	return factory
-}
-
-// RegisterFactory 注册VAD池工厂
-func (f *VADFactory) RegisterFactory(vadType string, factory VADPoolFactory) {
-	f.factories[vadType] = factory
-	logger.Infof("🔧 Registered VAD factory for type: %s", vadType)
-}
-
-// CreateVADPool 根据配置创建VAD池
-func (f *VADFactory) CreateVADPool() (VADPoolInterface, error) {
-	vadType := config.GlobalConfig.VAD.Provider
-
-	logger.Infof("🔧 Creating VAD pool with type: %s", vadType)
-
-	factory, exists := f.factories[vadType]
-	if !exists {
-		return nil, fmt.Errorf("unsupported VAD type: %s", vadType)
-	}
-
-	// 根据VAD类型创建配置
-	var config interface{}
-	var err error
-
-	switch vadType {
-	case SILERO_TYPE:
-		config, err = f.createSileroConfig()
-	// case TEN_VAD_TYPE:
-	// 	config, err = f.createTenVADConfig()
-	default:
-		return nil, fmt.Errorf("unsupported VAD type: %s", vadType)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create config for %s: %v", vadType, err)
-	}
-
-	// 使用工厂创建池
-	pool, err := factory.CreatePool(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create %s VAD pool: %v", vadType, err)
-	}
-
-	return pool, nil
-}
-
-// createSileroConfig 创建Silero VAD配置
-func (f *VADFactory) createSileroConfig() (*SileroVADConfig, error) {
-	// 创建VAD配置
-	vadConfig := &sherpa.VadModelConfig{
-		SileroVad: sherpa.SileroVadModelConfig{
-			Model:              config.GlobalConfig.VAD.SileroVAD.ModelPath,
-			Threshold:          config.GlobalConfig.VAD.SileroVAD.Threshold,
-			MinSilenceDuration: config.GlobalConfig.VAD.SileroVAD.MinSilenceDuration,
-			MinSpeechDuration:  config.GlobalConfig.VAD.SileroVAD.MinSpeechDuration,
-			WindowSize:         config.GlobalConfig.VAD.SileroVAD.WindowSize,
-			MaxSpeechDuration:  config.GlobalConfig.VAD.SileroVAD.MaxSpeechDuration,
-		},
-		SampleRate: config.GlobalConfig.Audio.SampleRate,
-		NumThreads: config.GlobalConfig.Recognition.NumThreads,
-		Provider:   config.GlobalConfig.Recognition.Provider,
-		Debug:      0,
-	}
-
-	return &SileroVADConfig{
-		ModelConfig:       vadConfig,
-		BufferSizeSeconds: config.GlobalConfig.VAD.SileroVAD.BufferSizeSeconds,
-		PoolSize:          config.GlobalConfig.VAD.PoolSize,
-		MaxIdle:           0, // 暂时不支持MaxIdle
-	}, nil
-}
-
-// createTenVADConfig 创建TEN-VAD配置 - Disabled for macOS
-// func (f *VADFactory) createTenVADConfig() (*TenVADConfig, error) {
-// 	return &TenVADConfig{
-// 		HopSize:   config.GlobalConfig.VAD.TenVAD.HopSize,
-// 		Threshold: config.GlobalConfig.VAD.Threshold,
-// 		PoolSize:  config.GlobalConfig.VAD.PoolSize,
-// 		MaxIdle:   0, // 暂时不支持MaxIdle
-// 	}, nil
-// }
-
-// GetVADType 获取当前VAD类型
-func (f *VADFactory) GetVADType() string {
-	return config.GlobalConfig.VAD.Provider
-}
-
-// GetSupportedTypes 获取支持的VAD类型
-func (f *VADFactory) GetSupportedTypes() []string {
-	types := make([]string, 0, len(f.factories))
-	for vadType := range f.factories {
-		types = append(types, vadType)
-	}
-	return types
-}
-
-// SileroVADPoolFactory Silero VAD池工厂
-type SileroVADPoolFactory struct{}
-
-// CreatePool 创建Silero VAD池
-func (f *SileroVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
-	sileroConfig, ok := config.(*SileroVADConfig)
-	if !ok {
-		return nil, fmt.Errorf("invalid config type for Silero VAD")
-	}
-
-	pool := NewSileroVADPool(sileroConfig)
-	return pool, nil
-}
-
-// GetSupportedTypes 获取支持的VAD类型
-func (f *SileroVADPoolFactory) GetSupportedTypes() []string {
-	return []string{SILERO_TYPE}
-}
-
-// TenVADPoolFactory TEN-VAD池工厂 - Disabled for macOS
-// type TenVADPoolFactory struct{}
-// 
-// // CreatePool 创建TEN-VAD池
-// func (f *TenVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
-// 	tenVADConfig, ok := config.(*TenVADConfig)
-// 	if !ok {
-// 		return nil, fmt.Errorf("invalid config type for TEN-VAD")
-// 	}
-// 
-// 	pool := NewTenVADPool(tenVADConfig)
-// 	return pool, nil
-// }
-// 
-// // GetSupportedTypes 获取支持的VAD类型
-// func (f *TenVADPoolFactory) GetSupportedTypes() []string {
-// 	return []string{TEN_VAD_TYPE}
-// }
+package pool
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"asr_server/config"
+	"asr_server/internal/logger"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"golang.org/x/sys/cpu"
+)
+
+// VADFactory VAD工厂
+type VADFactory struct {
+	factories map[string]VADPoolFactory
+}
+
+// NewVADFactory 创建新的VAD工厂
+func NewVADFactory() *VADFactory {
+	factory := &VADFactory{
+		factories: make(map[string]VADPoolFactory),
+	}
+
+	// 注册支持的VAD类型；TEN-VAD通过registerTenVADFactory按构建平台决定是否注册
+	// （darwin下保持禁用，见vad_factory_ten_darwin.go）
+	factory.RegisterFactory(SILERO_TYPE, &SileroVADPoolFactory{})
+	factory.RegisterFactory(WEBRTC_TYPE, &WebRTCVADPoolFactory{})
+	factory.RegisterFactory(ENERGY_TYPE, &EnergyVADPoolFactory{})
+	registerTenVADFactory(factory)
+
+	return factory
+}
+
+// RegisterFactory 注册VAD池工厂
+func (f *VADFactory) RegisterFactory(vadType string, factory VADPoolFactory) {
+	f.factories[vadType] = factory
+	logger.Infof("🔧 Registered VAD factory for type: %s", vadType)
+}
+
+// CreateVADPool 根据配置创建VAD池；provider为"auto"时会先探测模型文件和CPU特性
+// 选出一个具体的后端类型，而不是自己单独做一套创建逻辑，这样选中后的行为与
+// 显式配置该类型完全一致
+func (f *VADFactory) CreateVADPool() (VADPoolInterface, error) {
+	vadType := config.Load().VAD.Provider
+	if vadType == AUTO_VAD_TYPE {
+		vadType = f.resolveAutoType()
+		logger.Infof("🔍 VAD provider=auto resolved to: %s", vadType)
+	}
+
+	logger.Infof("🔧 Creating VAD pool with type: %s", vadType)
+
+	factory, exists := f.factories[vadType]
+	if !exists {
+		return nil, fmt.Errorf("unsupported VAD type: %s", vadType)
+	}
+
+	// 根据VAD类型创建配置
+	var vadConfig interface{}
+	var err error
+
+	switch vadType {
+	case SILERO_TYPE:
+		vadConfig, err = f.createSileroConfig()
+	case TEN_VAD_TYPE:
+		vadConfig, err = f.createTenVADConfig()
+	case WEBRTC_TYPE:
+		vadConfig, err = f.createWebRTCConfig()
+	case ENERGY_TYPE:
+		vadConfig, err = f.createEnergyConfig()
+	default:
+		return nil, fmt.Errorf("unsupported VAD type: %s", vadType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config for %s: %v", vadType, err)
+	}
+
+	// 使用工厂创建池
+	pool, err := factory.CreatePool(vadConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s VAD pool: %v", vadType, err)
+	}
+
+	return pool, nil
+}
+
+// resolveAutoType 探测模型文件可用性和CPU特性，挑选一个具体的VAD后端：
+// Silero精度最好但依赖onnxruntime + AVX2和2MB模型文件，都满足才选它（AVX2只在amd64上
+// 检查，其他架构视为满足）；否则退化到不依赖模型文件的纯Go WebRTC风格VAD
+func (f *VADFactory) resolveAutoType() string {
+	cfg := config.Load()
+
+	_, modelErr := os.Stat(cfg.VAD.SileroVAD.ModelPath)
+	hasAVX2 := runtime.GOARCH != "amd64" || cpu.X86.HasAVX2
+	if modelErr == nil && hasAVX2 {
+		if _, ok := f.factories[SILERO_TYPE]; ok {
+			logger.Infof("🔍 auto: silero model present and AVX2=%v, selecting %s", hasAVX2, SILERO_TYPE)
+			return SILERO_TYPE
+		}
+	}
+
+	logger.Infof("🔍 auto: silero model missing (err=%v) or AVX2 unavailable, falling back to %s", modelErr, WEBRTC_TYPE)
+	return WEBRTC_TYPE
+}
+
+// createSileroConfig 创建Silero VAD配置
+func (f *VADFactory) createSileroConfig() (*SileroVADConfig, error) {
+	// 创建VAD配置
+	vadConfig := &sherpa.VadModelConfig{
+		SileroVad: sherpa.SileroVadModelConfig{
+			Model:              config.Load().VAD.SileroVAD.ModelPath,
+			Threshold:          config.Load().VAD.SileroVAD.Threshold,
+			MinSilenceDuration: config.Load().VAD.SileroVAD.MinSilenceDuration,
+			MinSpeechDuration:  config.Load().VAD.SileroVAD.MinSpeechDuration,
+			WindowSize:         config.Load().VAD.SileroVAD.WindowSize,
+			MaxSpeechDuration:  config.Load().VAD.SileroVAD.MaxSpeechDuration,
+		},
+		SampleRate: config.Load().Audio.SampleRate,
+		NumThreads: config.Load().Recognition.NumThreads,
+		Provider:   config.Load().Recognition.Provider,
+		Debug:      0,
+	}
+
+	return &SileroVADConfig{
+		ModelConfig:       vadConfig,
+		BufferSizeSeconds: config.Load().VAD.SileroVAD.BufferSizeSeconds,
+		PoolSize:          config.Load().VAD.PoolSize,
+		MaxIdle:           0, // 暂时不支持MaxIdle
+		MaxTempInstances:  config.Load().VAD.SileroVAD.MaxTempInstances,
+	}, nil
+}
+
+// createTenVADConfig 创建TEN-VAD配置
+func (f *VADFactory) createTenVADConfig() (*TenVADConfig, error) {
+	return &TenVADConfig{
+		HopSize:          config.Load().VAD.TenVAD.HopSize,
+		SampleRate:       config.Load().Audio.SampleRate,
+		MinSpeechFrames:  config.Load().VAD.TenVAD.MinSpeechFrames,
+		MaxSilenceFrames: config.Load().VAD.TenVAD.MaxSilenceFrames,
+		PoolSize:         config.Load().VAD.PoolSize,
+		MaxIdle:          0, // 暂时不支持MaxIdle
+	}, nil
+}
+
+// createWebRTCConfig 创建WebRTC风格VAD配置
+func (f *VADFactory) createWebRTCConfig() (*WebRTCVADConfig, error) {
+	return &WebRTCVADConfig{
+		Aggressiveness:     config.Load().VAD.WebRTCVAD.Aggressiveness,
+		FrameDurationMs:    config.Load().VAD.WebRTCVAD.FrameDurationMs,
+		SampleRate:         config.Load().Audio.SampleRate,
+		MinSpeechDuration:  config.Load().VAD.WebRTCVAD.MinSpeechDuration,
+		MaxSpeechDuration:  config.Load().VAD.WebRTCVAD.MaxSpeechDuration,
+		MinSilenceDuration: config.Load().VAD.WebRTCVAD.MinSilenceDuration,
+		PoolSize:           config.Load().VAD.PoolSize,
+		MaxIdle:            0, // 暂时不支持MaxIdle
+	}, nil
+}
+
+// createEnergyConfig 创建RMS/ZCR能量VAD配置
+func (f *VADFactory) createEnergyConfig() (*EnergyVADConfig, error) {
+	return &EnergyVADConfig{
+		RMSThreshold:       config.Load().VAD.EnergyVAD.RMSThreshold,
+		ZCRThreshold:       config.Load().VAD.EnergyVAD.ZCRThreshold,
+		FrameDurationMs:    config.Load().VAD.EnergyVAD.FrameDurationMs,
+		SampleRate:         config.Load().Audio.SampleRate,
+		MinSpeechDuration:  config.Load().VAD.EnergyVAD.MinSpeechDuration,
+		MaxSpeechDuration:  config.Load().VAD.EnergyVAD.MaxSpeechDuration,
+		MinSilenceDuration: config.Load().VAD.EnergyVAD.MinSilenceDuration,
+		PoolSize:           config.Load().VAD.PoolSize,
+		MaxIdle:            0, // 暂时不支持MaxIdle
+	}, nil
+}
+
+// GetVADType 获取当前VAD类型
+func (f *VADFactory) GetVADType() string {
+	return config.Load().VAD.Provider
+}
+
+// GetSupportedTypes 获取支持的VAD类型
+func (f *VADFactory) GetSupportedTypes() []string {
+	types := make([]string, 0, len(f.factories))
+	for vadType := range f.factories {
+		types = append(types, vadType)
+	}
+	return types
+}
+
+// SileroVADPoolFactory Silero VAD池工厂
+type SileroVADPoolFactory struct{}
+
+// CreatePool 创建Silero VAD池
+func (f *SileroVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
+	sileroConfig, ok := config.(*SileroVADConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid config type for Silero VAD")
+	}
+
+	pool := NewSileroVADPool(sileroConfig)
+	return pool, nil
+}
+
+// GetSupportedTypes 获取支持的VAD类型
+func (f *SileroVADPoolFactory) GetSupportedTypes() []string {
+	return []string{SILERO_TYPE}
+}
@@ -0,0 +1,7 @@
+//go:build darwin
+
+package pool
+
+// registerTenVADFactory 在macOS上是空实现，TEN-VAD保持禁用；
+// 非darwin版本见ten_vad.go
+func registerTenVADFactory(f *VADFactory) {}
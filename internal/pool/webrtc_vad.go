@@ -0,0 +1,339 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"asr_server/internal/logger"
+)
+
+// WebRTCVADConfig 纯Go WebRTC风格VAD配置：按aggressiveness(0-3)门控帧能量与
+// 频谱平坦度，不依赖任何ONNX模型文件
+type WebRTCVADConfig struct {
+	Aggressiveness     int
+	FrameDurationMs    int
+	SampleRate         int
+	MinSpeechDuration  float32
+	MaxSpeechDuration  float32
+	MinSilenceDuration float32
+	PoolSize           int
+	MaxIdle            int
+}
+
+// WebRTCVADInstance 纯Go VAD实例：逐帧做能量/频谱平坦度gating，用状态机攒出完整语音段，
+// 语音段队列的形状（IsEmpty/Front/Pop返回*VADSegment）与sherpa.VoiceActivityDetector保持一致，
+// 使manager.go里的语音段收集循环无需为每种后端单独写一套
+type WebRTCVADInstance struct {
+	ID       int
+	LastUsed int64
+	InUse    int32
+	mu       sync.Mutex
+
+	frameSize        int
+	energyThreshold  float32
+	flatnessMax      float32
+	minSpeechSamples int
+	maxSpeechSamples int
+	minSilenceFrames int
+
+	frameBuf      []float32
+	inSpeech      bool
+	current       []float32
+	silenceFrames int
+	segments      []*VADSegment
+}
+
+// AcceptWaveform 喂入新采样；内部按frameSize分帧做VAD gating并维护语音段状态机
+func (i *WebRTCVADInstance) AcceptWaveform(samples []float32) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.frameBuf = append(i.frameBuf, samples...)
+	for len(i.frameBuf) >= i.frameSize {
+		frame := i.frameBuf[:i.frameSize]
+		i.frameBuf = i.frameBuf[i.frameSize:]
+		i.processFrame(frame)
+	}
+}
+
+func (i *WebRTCVADInstance) isSpeechFrame(frame []float32) bool {
+	return frameRMS(frame) > i.energyThreshold && frameSpectralFlatness(frame) < i.flatnessMax
+}
+
+func (i *WebRTCVADInstance) processFrame(frame []float32) {
+	if i.isSpeechFrame(frame) {
+		i.inSpeech = true
+		i.silenceFrames = 0
+		i.current = append(i.current, frame...)
+		if len(i.current) >= i.maxSpeechSamples {
+			i.flushSegment()
+		}
+		return
+	}
+
+	if !i.inSpeech {
+		return
+	}
+
+	i.silenceFrames++
+	i.current = append(i.current, frame...)
+	if i.silenceFrames >= i.minSilenceFrames {
+		i.flushSegment()
+	}
+}
+
+// flushSegment 结束当前语音段：太短则丢弃，否则入队，与sherpa VAD "完成一段即入队"的语义一致
+func (i *WebRTCVADInstance) flushSegment() {
+	segment := i.current
+	i.current = nil
+	i.inSpeech = false
+	i.silenceFrames = 0
+
+	if len(segment) < i.minSpeechSamples {
+		return
+	}
+	if len(segment) > i.maxSpeechSamples {
+		segment = segment[:i.maxSpeechSamples]
+	}
+	i.segments = append(i.segments, &VADSegment{Samples: segment})
+}
+
+// IsEmpty 是否有已完成的语音段在排队，镜像sherpa.VoiceActivityDetector.IsEmpty()
+func (i *WebRTCVADInstance) IsEmpty() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.segments) == 0
+}
+
+// Front 查看队首语音段，镜像sherpa.VoiceActivityDetector.Front()
+func (i *WebRTCVADInstance) Front() *VADSegment {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return nil
+	}
+	return i.segments[0]
+}
+
+// Pop 弹出队首语音段，镜像sherpa.VoiceActivityDetector.Pop()
+func (i *WebRTCVADInstance) Pop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.segments) == 0 {
+		return
+	}
+	i.segments = i.segments[1:]
+}
+
+// GetID 获取实例ID
+func (i *WebRTCVADInstance) GetID() int { return i.ID }
+
+// GetType 获取VAD类型
+func (i *WebRTCVADInstance) GetType() string { return WEBRTC_TYPE }
+
+// IsInUse 检查是否在使用中
+func (i *WebRTCVADInstance) IsInUse() bool { return atomic.LoadInt32(&i.InUse) == 1 }
+
+// SetInUse 设置使用状态
+func (i *WebRTCVADInstance) SetInUse(inUse bool) {
+	if inUse {
+		atomic.StoreInt32(&i.InUse, 1)
+	} else {
+		atomic.StoreInt32(&i.InUse, 0)
+	}
+}
+
+// GetLastUsed 获取最后使用时间
+func (i *WebRTCVADInstance) GetLastUsed() int64 { return atomic.LoadInt64(&i.LastUsed) }
+
+// SetLastUsed 设置最后使用时间
+func (i *WebRTCVADInstance) SetLastUsed(timestamp int64) { atomic.StoreInt64(&i.LastUsed, timestamp) }
+
+// Reset 重置实例状态，归还到池前清空残留语音段和状态机
+func (i *WebRTCVADInstance) Reset() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.frameBuf = i.frameBuf[:0]
+	i.current = nil
+	i.inSpeech = false
+	i.silenceFrames = 0
+	i.segments = nil
+	return nil
+}
+
+// Destroy 销毁实例；纯Go结构，没有底层资源需要释放
+func (i *WebRTCVADInstance) Destroy() error {
+	return nil
+}
+
+// WebRTCVADPool 纯Go VAD资源池，结构与SileroVADPool保持一致，便于GetStats汇总
+type WebRTCVADPool struct {
+	instances []*WebRTCVADInstance
+	available chan VADInstanceInterface
+	config    *WebRTCVADConfig
+
+	totalCreated int64
+	totalReused  int64
+	totalActive  int64
+
+	mu     sync.RWMutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWebRTCVADPool 创建新的纯Go VAD资源池
+func NewWebRTCVADPool(config *WebRTCVADConfig) *WebRTCVADPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebRTCVADPool{
+		instances: make([]*WebRTCVADInstance, 0, config.PoolSize),
+		available: make(chan VADInstanceInterface, config.PoolSize),
+		config:    config,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (p *WebRTCVADPool) newInstance(id int) *WebRTCVADInstance {
+	frameSize := p.config.SampleRate * p.config.FrameDurationMs / 1000
+	if frameSize <= 0 {
+		frameSize = 160
+	}
+	energyThreshold, flatnessMax := aggressivenessGates(p.config.Aggressiveness)
+	minSilenceFrames := int(p.config.MinSilenceDuration*1000) / p.config.FrameDurationMs
+	if minSilenceFrames <= 0 {
+		minSilenceFrames = 1
+	}
+
+	return &WebRTCVADInstance{
+		ID:               id,
+		LastUsed:         time.Now().UnixNano(),
+		frameSize:        frameSize,
+		energyThreshold:  energyThreshold,
+		flatnessMax:      flatnessMax,
+		minSpeechSamples: int(p.config.MinSpeechDuration * float32(p.config.SampleRate)),
+		maxSpeechSamples: int(p.config.MaxSpeechDuration * float32(p.config.SampleRate)),
+		minSilenceFrames: minSilenceFrames,
+	}
+}
+
+// Initialize 创建池中全部实例；纯Go结构体，创建成本可忽略，无需像Silero那样并行初始化
+func (p *WebRTCVADPool) Initialize() error {
+	logger.Infof("🔧 Initializing WebRTC-style VAD pool with %d instances...", p.config.PoolSize)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for idx := 0; idx < p.config.PoolSize; idx++ {
+		instance := p.newInstance(idx)
+		p.instances = append(p.instances, instance)
+		p.available <- instance
+		atomic.AddInt64(&p.totalCreated, 1)
+	}
+
+	logger.Infof("🚀 WebRTC-style VAD pool initialized with %d instances", len(p.instances))
+	return nil
+}
+
+// Get 获取VAD实例
+func (p *WebRTCVADPool) Get() (VADInstanceInterface, error) {
+	select {
+	case instance := <-p.available:
+		webrtcInstance := instance.(*WebRTCVADInstance)
+		if atomic.CompareAndSwapInt32(&webrtcInstance.InUse, 0, 1) {
+			instance.SetLastUsed(time.Now().UnixNano())
+			atomic.AddInt64(&p.totalReused, 1)
+			atomic.AddInt64(&p.totalActive, 1)
+			return instance, nil
+		}
+		select {
+		case p.available <- instance:
+		default:
+		}
+		return p.Get()
+	case <-time.After(100 * time.Millisecond):
+		logger.Warnf("⏰ WebRTC-style VAD pool timeout, creating new temporary instance")
+		instance := p.newInstance(-1)
+		instance.InUse = 1
+		atomic.AddInt64(&p.totalCreated, 1)
+		atomic.AddInt64(&p.totalActive, 1)
+		return instance, nil
+	case <-p.ctx.Done():
+		return nil, fmt.Errorf("WebRTC-style VAD pool is shutting down")
+	}
+}
+
+// Put 归还VAD实例
+func (p *WebRTCVADPool) Put(instance VADInstanceInterface) {
+	if instance == nil {
+		return
+	}
+	webrtcInstance := instance.(*WebRTCVADInstance)
+	if atomic.CompareAndSwapInt32(&webrtcInstance.InUse, 1, 0) {
+		instance.SetLastUsed(time.Now().UnixNano())
+		atomic.AddInt64(&p.totalActive, -1)
+		if err := instance.Reset(); err != nil {
+			logger.Warnf("⚠️ Failed to reset WebRTC-style VAD instance %d: %v", instance.GetID(), err)
+		}
+		select {
+		case p.available <- instance:
+		default:
+			instance.Destroy()
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (p *WebRTCVADPool) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return map[string]interface{}{
+		"vad_type":        WEBRTC_TYPE,
+		"pool_size":       p.config.PoolSize,
+		"max_idle":        p.config.MaxIdle,
+		"total_instances": len(p.instances),
+		"available_count": len(p.available),
+		"active_count":    atomic.LoadInt64(&p.totalActive),
+		"total_created":   atomic.LoadInt64(&p.totalCreated),
+		"total_reused":    atomic.LoadInt64(&p.totalReused),
+	}
+}
+
+// Shutdown 关闭VAD池
+func (p *WebRTCVADPool) Shutdown() {
+	logger.Infof("🛑 Shutting down WebRTC-style VAD pool...")
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+drain:
+	for {
+		select {
+		case instance := <-p.available:
+			instance.Destroy()
+		default:
+			break drain
+		}
+	}
+	p.instances = nil
+	close(p.available)
+	logger.Infof("✅ WebRTC-style VAD pool shutdown complete")
+}
+
+// WebRTCVADPoolFactory WebRTC风格VAD池工厂
+type WebRTCVADPoolFactory struct{}
+
+// CreatePool 创建WebRTC风格VAD池
+func (f *WebRTCVADPoolFactory) CreatePool(config interface{}) (VADPoolInterface, error) {
+	webrtcConfig, ok := config.(*WebRTCVADConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid config type for WebRTC-style VAD")
+	}
+	return NewWebRTCVADPool(webrtcConfig), nil
+}
+
+// GetSupportedTypes 获取支持的VAD类型
+func (f *WebRTCVADPoolFactory) GetSupportedTypes() []string {
+	return []string{WEBRTC_TYPE}
+}
@@ -0,0 +1,207 @@
+// Package recorder 借鉴MPD RecorderOutputPlugin的思路，订阅VAD分段边界（及可选的
+// 说话人归属判定结果），把完成的语音段落盘，供离线数据集构建和问题复盘使用。
+package recorder
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"asr_server/internal/codec"
+	"asr_server/internal/logger"
+)
+
+// SegmentEvent 描述一个已完成VAD分段的录音素材，由session.Manager在语音段收尾、
+// 完成说话人归属判定后推送给SegmentSink
+type SegmentEvent struct {
+	SessionID  string
+	SpeakerID  string // 为空时在文件名中落为"unknown"
+	Samples    []float32
+	SampleRate int
+	PreRoll    []float32 // speech_start之前截取的音频，写入时前置拼接，减少语音起始被截断的概率
+}
+
+// SegmentSink 接收已完成的VAD语音段。Recorder是当前唯一实现，
+// session包仅依赖本接口，不直接依赖recorder包的落盘细节
+type SegmentSink interface {
+	WriteSegment(evt SegmentEvent)
+	// CloseSession 会话结束时调用，落盘该会话尚未触发滚动的缓冲数据
+	CloseSession(sessionID string)
+}
+
+// Config 录音子系统配置，字段与config.Config.Recorder一一对应
+type Config struct {
+	Enabled          bool
+	OutputDir        string
+	Format           string // wav/flac/opus，对应codec.NewEncoder支持的格式名
+	MaxSizeBytes     int64  // 单个录音文件达到该大小（按原始PCM估算）即滚动
+	MaxAgeDays       int    // 单个录音文件存活超过该天数即滚动
+	IncludePreRollMs int
+}
+
+// sessionFile 某个会话当前正在累积、尚未落盘的录音文件。文件名在其创建时一次性确定，
+// 滚动时统一编码整段采样再压缩，避免为WAV/FLAC/Opus各自实现流式编码器
+type sessionFile struct {
+	openedAt   time.Time
+	speakerID  string
+	sampleRate int
+	samples    []float32
+}
+
+// Recorder 订阅VAD分段边界，将音频写入磁盘；enabled可通过热加载开关运行时切换，
+// 无需重启服务即可暂停/恢复录音
+type Recorder struct {
+	cfg     Config
+	enabled int32 // atomic bool
+
+	mu    sync.Mutex
+	files map[string]*sessionFile
+}
+
+// New 创建Recorder，cfg.Enabled决定初始开关状态
+func New(cfg Config) *Recorder {
+	r := &Recorder{cfg: cfg, files: make(map[string]*sessionFile)}
+	if cfg.Enabled {
+		atomic.StoreInt32(&r.enabled, 1)
+	}
+	return r
+}
+
+// SetEnabled 运行时切换录音开关，供HotReloadManager的"recorder"回调调用
+func (r *Recorder) SetEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&r.enabled, v)
+	logger.Infof("🔧 Recorder enabled=%v", enabled)
+}
+
+// WriteSegment 实现SegmentSink：将一个已完成的VAD语音段追加到该会话当前的录音文件，
+// 按MaxSizeBytes/MaxAgeDays滚动，旧文件滚动时落盘并gzip压缩
+func (r *Recorder) WriteSegment(evt SegmentEvent) {
+	if atomic.LoadInt32(&r.enabled) == 0 {
+		return
+	}
+	if len(evt.Samples) == 0 {
+		return
+	}
+
+	speakerID := evt.SpeakerID
+	if speakerID == "" {
+		speakerID = "unknown"
+	}
+
+	r.mu.Lock()
+	f, exists := r.files[evt.SessionID]
+	if !exists || r.needsRotation(f) {
+		if exists {
+			delete(r.files, evt.SessionID)
+			go r.flush(evt.SessionID, f)
+		}
+		f = &sessionFile{openedAt: time.Now(), speakerID: speakerID, sampleRate: evt.SampleRate}
+		r.files[evt.SessionID] = f
+	}
+
+	if len(evt.PreRoll) > 0 {
+		f.samples = append(f.samples, evt.PreRoll...)
+	}
+	f.samples = append(f.samples, evt.Samples...)
+	r.mu.Unlock()
+}
+
+// needsRotation 判断当前录音文件是否已达到滚动阈值；MaxSizeBytes按float32原始PCM
+// （4字节/采样）估算，是一个偏保守的近似值，实际编码后的FLAC/Opus文件通常更小
+func (r *Recorder) needsRotation(f *sessionFile) bool {
+	if f == nil {
+		return true
+	}
+	if r.cfg.MaxSizeBytes > 0 && int64(len(f.samples))*4 >= r.cfg.MaxSizeBytes {
+		return true
+	}
+	if r.cfg.MaxAgeDays > 0 && time.Since(f.openedAt) >= time.Duration(r.cfg.MaxAgeDays)*24*time.Hour {
+		return true
+	}
+	return false
+}
+
+// flush 编码并落盘一个会话的录音文件，文件名为{session_id}/{timestamp}_{speaker_id_or_unknown}.{ext}.gz
+func (r *Recorder) flush(sessionID string, f *sessionFile) {
+	if f == nil || len(f.samples) == 0 {
+		return
+	}
+
+	encoder, err := codec.NewEncoder(r.cfg.Format)
+	if err != nil {
+		logger.Errorf("Recorder: failed to create %s encoder: %v", r.cfg.Format, err)
+		return
+	}
+
+	encoded, err := encoder.Encode(f.samples, f.sampleRate)
+	if err != nil {
+		logger.Errorf("Recorder: failed to encode session %s recording: %v", sessionID, err)
+		return
+	}
+
+	dir := filepath.Join(r.cfg.OutputDir, sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Errorf("Recorder: failed to create output dir %s: %v", dir, err)
+		return
+	}
+
+	name := fmt.Sprintf("%d_%s%s.gz", f.openedAt.UnixMilli(), f.speakerID, encoder.Ext())
+	path := filepath.Join(dir, name)
+
+	if err := writeGzipFile(path, encoded); err != nil {
+		logger.Errorf("Recorder: failed to write recording file %s: %v", path, err)
+		return
+	}
+
+	logger.Infof("🎙️ Recorder: wrote %s (%d samples)", path, len(f.samples))
+}
+
+// writeGzipFile 将encoded内容gzip压缩后写入path
+func writeGzipFile(path string, encoded []byte) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	if _, err := gz.Write(encoded); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// CloseSession 立即落盘并结束某个会话当前缓冲的录音文件，会话关闭时调用，
+// 避免数据停留在内存中直到下一次WriteSegment触发滚动
+func (r *Recorder) CloseSession(sessionID string) {
+	r.mu.Lock()
+	f, exists := r.files[sessionID]
+	if exists {
+		delete(r.files, sessionID)
+	}
+	r.mu.Unlock()
+	if exists {
+		r.flush(sessionID, f)
+	}
+}
+
+// Shutdown 落盘所有会话当前缓冲的录音数据，服务退出前调用
+func (r *Recorder) Shutdown() {
+	r.mu.Lock()
+	files := r.files
+	r.files = make(map[string]*sessionFile)
+	r.mu.Unlock()
+
+	for sessionID, f := range files {
+		r.flush(sessionID, f)
+	}
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"asr_server/internal/bootstrap"
 	"asr_server/internal/handlers"
+	"asr_server/internal/metrics"
 	"asr_server/internal/ws"
 
 	"github.com/gin-gonic/gin"
@@ -14,12 +15,20 @@ func NewRouter(deps *bootstrap.AppDependencies) *gin.Engine {
 	ginRouter.Use(gin.Recovery())
 	// TODO: 根据需要注入 gin.Logger()
 
+	// 注入per-IP/CIDR连接限制器：HandleWebSocket的Acquire/Release准入控制和
+	// Upgrader.CheckOrigin共用同一个实例，必须在注册/ws路由之前完成
+	ws.SetConnectionLimiter(deps.ConnLimiter)
+
 	// 注册基础路由
 	ginRouter.GET("/ws", func(c *gin.Context) {
-		ws.HandleWebSocket(c.Writer, c.Request, deps.SessionManager, deps.GlobalRecognizer)
+		ws.HandleWebSocket(c.Writer, c.Request, deps.SessionManager)
 	})
 	ginRouter.GET("/health", handlers.HealthHandler(deps))
+	ginRouter.GET("/livez", handlers.LivezHandler())
+	ginRouter.GET("/readyz", handlers.ReadyzHandler(deps))
 	ginRouter.GET("/stats", handlers.StatsHandler(deps))
+	ginRouter.GET("/metrics", gin.WrapH(metrics.Handler()))
+	ginRouter.GET("/api/v1/sessions/:session_id/turns", handlers.TurnHistoryHandler(deps))
 
 	// 静态文件服务
 	ginRouter.Static("/static", "./static")
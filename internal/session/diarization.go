@@ -0,0 +1,118 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"asr_server/internal/speaker"
+)
+
+const (
+	// anonymousMatchThreshold 匿名说话人质心的余弦相似度命中阈值
+	anonymousMatchThreshold = 0.6
+	// centroidEMAAlpha 质心指数滑动平均的更新权重
+	centroidEMAAlpha = 0.2
+)
+
+// TurnRecord 记录一次说话人轮次，供 /turns 历史查询接口使用
+type TurnRecord struct {
+	TurnID    int       `json:"turn_id"`
+	SpeakerID string    `json:"speaker_id"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// diarizationState 每个会话维护一份匿名说话人质心缓存和turn计数，
+// 用于在未启用声纹库或遇到未注册说话人时也能区分“第几个人在说话”
+type diarizationState struct {
+	mu             sync.Mutex
+	currentSpeaker string
+	nextTurnID     int
+	nextUnknownID  int
+	centroids      map[string][]float32
+	turns          []TurnRecord
+}
+
+func newDiarizationState() *diarizationState {
+	return &diarizationState{centroids: make(map[string][]float32)}
+}
+
+// matchOrCreateAnonymous 在匿名质心缓存中寻找最相似的说话人；命中则做EMA更新，否则新建一个
+func (s *diarizationState) matchOrCreateAnonymous(embedding []float32) string {
+	bestID := ""
+	bestSim := float32(0)
+	for id, centroid := range s.centroids {
+		sim := speaker.CosineSimilarity(embedding, centroid)
+		if sim > bestSim {
+			bestSim = sim
+			bestID = id
+		}
+	}
+
+	if bestID != "" && bestSim >= anonymousMatchThreshold {
+		centroid := s.centroids[bestID]
+		for i := range centroid {
+			centroid[i] = centroid[i]*(1-centroidEMAAlpha) + embedding[i]*centroidEMAAlpha
+		}
+		return bestID
+	}
+
+	s.nextUnknownID++
+	id := fmt.Sprintf("unknown_%d", s.nextUnknownID)
+	s.centroids[id] = append([]float32{}, embedding...)
+	return id
+}
+
+// recordTurn 追加一条轮次记录，供turn history接口查询
+func (s *diarizationState) recordTurn(turnID int, speakerID, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = append(s.turns, TurnRecord{TurnID: turnID, SpeakerID: speakerID, Text: text, Timestamp: time.Now()})
+}
+
+// identifySegmentSpeaker 对一段VAD语音段做说话人归属判定：
+// 先尝试匹配已注册声纹库，未命中则在本会话的匿名质心缓存中匹配或新建一个。
+// 说话人标签相较上一次发生变化时，turn_id自增，形成change-point分段。
+func (m *Manager) identifySegmentSpeaker(sess *Session, samples []float32, sampleRate int) (string, int) {
+	if m.speakerManager == nil || sess.diarization == nil {
+		return "", 0
+	}
+
+	state := sess.diarization
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	speakerID := ""
+	if result, err := m.speakerManager.IdentifySpeaker(samples, sampleRate); err == nil && result.Identified {
+		speakerID = result.SpeakerID
+	} else if embedding, err := m.speakerManager.ExtractEmbedding(samples, sampleRate); err == nil {
+		speakerID = state.matchOrCreateAnonymous(embedding)
+	}
+
+	if speakerID == "" {
+		return "", state.nextTurnID
+	}
+
+	if speakerID != state.currentSpeaker {
+		state.nextTurnID++
+		state.currentSpeaker = speakerID
+	}
+
+	return speakerID, state.nextTurnID
+}
+
+// GetTurnHistory 返回某个会话目前为止的说话人轮次历史
+func (m *Manager) GetTurnHistory(sessionID string) ([]TurnRecord, bool) {
+	sess, exists := m.GetSession(sessionID)
+	if !exists || sess.diarization == nil {
+		return nil, false
+	}
+
+	sess.diarization.mu.Lock()
+	defer sess.diarization.mu.Unlock()
+
+	history := make([]TurnRecord, len(sess.diarization.turns))
+	copy(history, sess.diarization.turns)
+	return history, true
+}
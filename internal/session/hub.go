@@ -0,0 +1,194 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"asr_server/internal/logger"
+)
+
+// roomBroadcastBuffer 房间内部的发布缓冲区大小，producer一侧的publish不应被观察者拖慢
+const roomBroadcastBuffer = 64
+
+// Room 承载一个生产会话的转写结果，供多个观察者会话订阅
+type Room struct {
+	ID                string
+	ProducerSessionID string
+
+	mu          sync.RWMutex
+	subscribers map[string]*Session
+
+	messagesBroadcast int64
+	messagesDropped   int64
+
+	incoming chan interface{}
+	done     chan struct{}
+}
+
+func newRoom(id string) *Room {
+	room := &Room{
+		ID:                id,
+		ProducerSessionID: id,
+		subscribers:       make(map[string]*Session),
+		incoming:          make(chan interface{}, roomBroadcastBuffer),
+		done:              make(chan struct{}),
+	}
+	go room.run()
+	return room
+}
+
+// run 是房间独享的fan-out协程，逐条从incoming取出消息分发给当前所有订阅者
+func (r *Room) run() {
+	for {
+		select {
+		case msg := <-r.incoming:
+			r.fanOut(msg)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// broadcast 供生产会话发布消息，对incoming的写入是非阻塞的，避免观察者反压到ASR主流程
+func (r *Room) broadcast(msg interface{}) {
+	select {
+	case r.incoming <- msg:
+	default:
+		atomic.AddInt64(&r.messagesDropped, 1)
+	}
+}
+
+// fanOut 把一条消息非阻塞地投递给每个订阅者的SendQueue；队列已满时丢弃该订阅者最老的一条再重试，
+// 这样单个慢观察者不会无限堆积，也不会影响其他订阅者
+func (r *Room) fanOut(msg interface{}) {
+	r.mu.RLock()
+	subs := make([]*Session, 0, len(r.subscribers))
+	for _, sub := range r.subscribers {
+		subs = append(subs, sub)
+	}
+	r.mu.RUnlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.SendQueue <- msg:
+			atomic.AddInt64(&r.messagesBroadcast, 1)
+			continue
+		default:
+		}
+
+		select {
+		case <-sub.SendQueue:
+			atomic.AddInt64(&r.messagesDropped, 1)
+		default:
+		}
+
+		select {
+		case sub.SendQueue <- msg:
+			atomic.AddInt64(&r.messagesBroadcast, 1)
+		default:
+			atomic.AddInt64(&r.messagesDropped, 1)
+		}
+	}
+}
+
+func (r *Room) stats() map[string]interface{} {
+	r.mu.RLock()
+	subscriberCount := len(r.subscribers)
+	r.mu.RUnlock()
+
+	return map[string]interface{}{
+		"subscribers":        subscriberCount,
+		"messages_broadcast": atomic.LoadInt64(&r.messagesBroadcast),
+		"messages_dropped":   atomic.LoadInt64(&r.messagesDropped),
+	}
+}
+
+// Hub 管理所有房间，一个生产会话对应一个以其sessionID命名的房间
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]*Room
+}
+
+func newHub() *Hub {
+	return &Hub{rooms: make(map[string]*Room)}
+}
+
+func (h *Hub) getRoom(roomID string) (*Room, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	room, exists := h.rooms[roomID]
+	return room, exists
+}
+
+func (h *Hub) getOrCreateRoom(roomID string) *Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room, exists := h.rooms[roomID]
+	if !exists {
+		room = newRoom(roomID)
+		h.rooms[roomID] = room
+	}
+	return room
+}
+
+// joinRoom 将订阅者加入房间，房间不存在时惰性创建
+func (h *Hub) joinRoom(subscriber *Session, roomID string) {
+	room := h.getOrCreateRoom(roomID)
+	room.mu.Lock()
+	room.subscribers[subscriber.ID] = subscriber
+	room.mu.Unlock()
+	logger.Infof("👀 Session %s subscribed to room %s", subscriber.ID, roomID)
+}
+
+// leaveRoom 将订阅者从房间移除，房间不存在时忽略
+func (h *Hub) leaveRoom(sessionID, roomID string) {
+	room, exists := h.getRoom(roomID)
+	if !exists {
+		return
+	}
+	room.mu.Lock()
+	delete(room.subscribers, sessionID)
+	room.mu.Unlock()
+}
+
+// removeSessionEverywhere 锁序固定为 Hub.mu -> Room.mu，与 closeSession 在 Manager.mu 之后
+// 调用本方法保持一致的加锁顺序，避免与其他路径形成环路
+func (h *Hub) removeSessionEverywhere(sessionID string) {
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		room.mu.Lock()
+		delete(room.subscribers, sessionID)
+		room.mu.Unlock()
+	}
+}
+
+// closeRoomIfProducer 在生产会话关闭时销毁其房间并停止fan-out协程
+func (h *Hub) closeRoomIfProducer(sessionID string) {
+	h.mu.Lock()
+	room, exists := h.rooms[sessionID]
+	if exists {
+		delete(h.rooms, sessionID)
+	}
+	h.mu.Unlock()
+
+	if exists {
+		close(room.done)
+	}
+}
+
+func (h *Hub) stats() map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make(map[string]interface{}, len(h.rooms))
+	for id, room := range h.rooms {
+		rooms[id] = room.stats()
+	}
+	return rooms
+}
@@ -8,12 +8,18 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"asr_server/config"
+	"asr_server/internal/asr"
+	"asr_server/internal/codec"
 	"asr_server/internal/logger"
+	"asr_server/internal/metrics"
 	"asr_server/internal/pool"
-
-	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+	"asr_server/internal/recorder"
+	"asr_server/internal/speaker"
+	"asr_server/internal/tracing"
 )
 
 // Session WebSocket会话
@@ -32,24 +38,107 @@ type Session struct {
 
 	// 活跃性检测
 	lastActivity time.Time
+	CreatedAt    int64 // 会话创建时间（UnixNano），用于计算MaxSessionAge硬上限
+
+	// 端点检测状态：isInSpeech/currentSegment由各VAD后端的process*方法维护
+	isInSpeech     bool
+	currentSegment []float32
+
+	// 流式partial/端点事件：currentSegment在speech_end前持续累积供cheap partial解码，
+	// segmentID在每次speech_start时递增，activeDecode记录当前语音段的final解码协程，
+	// 供下一次speech_start检测barge-in时取消
+	segmentID     int64
+	lastPartialAt int64 // UnixNano，用于按PartialIntervalMs节流partial解码
+	segMu         sync.Mutex
+	activeDecode  *segmentDecode
+
+	// diarization 实时说话人分离状态，仅在Manager配置了speakerManager时非空
+	diarization *diarizationState
+
+	// 音频前端编解码协商结果：CodecName/CodecSampleRate由握手(子协议或config控制消息)确定，
+	// Decoder在首次收到音频帧时才懒创建，避免协商发生在CreateSession之后也能正确生效
+	CodecName       string
+	CodecSampleRate int
+	Decoder         codec.Decoder
+
+	// recognizer/vadPool在CreateSession时通过Manager.recognizerFactory/vadStrategy解析一次并固定，
+	// 使得同一会话全程使用同一套引擎实例，同时允许不同会话路由到不同引擎
+	recognizer asr.Recognizer
+	vadPool    pool.VADPoolInterface
 
-	// ten-vad 相关
-	isInSpeech        bool
-	currentSegment    []float32
-	silenceFrameCount int
+	// preRoll 持续滚动保留最近Manager.preRollMs毫秒的原始采样，供segmentSink使用；
+	// pendingPreRoll在每次speech_start时从preRoll快照而来，并在该语音段落盘后清空，
+	// 避免同一段pre-roll被重复写入同一次speech_start产生的多个分段
+	preRoll        []float32
+	pendingPreRoll []float32
+
+	// mTLS鉴权：PeerCertSubject/PeerCertCN由ws.HandleWebSocket在握手后通过
+	// Manager.SetPeerIdentity填入，仅在server.tls.client_ca_file开启且客户端提供了
+	// 证书时非空，供下游speaker/ASR等handler据此做基于证书身份的鉴权
+	PeerCertSubject string
+	PeerCertCN      string
+
+	// 会话恢复：resumeIssuedAt在CreateSession时固定一次，是resume_token里HMAC覆盖的
+	// issued_at分量；draining标记该会话当前是否处于RemoveSession与grace period销毁之间
+	// 的"等待Resume重新绑定连接"窗口，resumeCh在每次成功Resume时被关闭并替换，
+	// 供sendLoop在Conn被置空期间阻塞等待而不是对着失效连接报错
+	resumeIssuedAt int64
+	draining       bool
+	resumeCh       chan struct{}
+}
+
+// segmentDecode 跟踪某一语音段的final解码协程，用于barge-in场景下取消过时的解码
+type segmentDecode struct {
+	id     int64
+	cancel context.CancelFunc
 }
 
 // Manager 会话管理器
 type Manager struct {
-	sessions   map[string]*Session
-	recognizer *sherpa.OfflineRecognizer
-	vadPool    pool.VADPoolInterface
-	mu         sync.RWMutex
+	sessions map[string]*Session
+	mu       sync.RWMutex
+
+	// 通过功能选项注入的可插拔策略，替代此前写死在构造函数里的单一recognizer/vadPool
+	recognizerFactory    func(SessionMeta) asr.Recognizer
+	vadStrategy          func(SessionMeta) pool.VADPoolInterface
+	decoderRegistry      *codec.Factory
+	metricsSink          prometheus.Registerer
+	segmentPostProcessor func([]float32) []float32
+	speakerManager       *speaker.Manager
+	segmentSink          recorder.SegmentSink
+	preRollMs            int
+	preRollSamples       int // 由preRollMs按Audio.SampleRate换算而来，NewManager中计算一次
+
+	// vadPools 记录所有已被vadStrategy解析出的VAD池（按实例去重），供GetStats汇总统计；
+	// 多数部署仍只有一个池，这里兼容vadStrategy按会话路由到不同池的场景
+	vadPoolsMu sync.Mutex
+	vadPools   map[pool.VADPoolInterface]struct{}
 
 	// 统计信息
-	totalSessions  int64
-	activeSessions int64
-	totalMessages  int64
+	totalSessions   int64
+	activeSessions  int64
+	totalMessages   int64
+	expiredSessions int64
+
+	// 会话过期回收：小顶堆按expiresAt排序，reaper goroutine据此做O(log n)调度
+	idleTimeout   time.Duration
+	maxSessionAge time.Duration
+	expiryHeap    expiryHeap
+	expiryMu      sync.Mutex
+	expiryWake    chan struct{}
+	nextExpiryNs  int64
+
+	// 会话恢复：resumeSecret为空表示整个功能禁用（RemoveSession退化为立即销毁）；
+	// resumeTTL限制resume_token的有效期；drainGrace是RemoveSession后会话留在
+	// drainingSessions中等待Resume重新绑定连接的宽限期，超时仍未恢复则按正常流程销毁
+	resumeSecret     []byte
+	resumeTTL        time.Duration
+	drainGrace       time.Duration
+	drainingMu       sync.Mutex
+	drainingSessions map[string]*Session
+
+	// hub 支持一个生产会话的转写结果被多个观察者会话订阅
+	hub *Hub
 
 	// 清理
 	ctx    context.Context
@@ -66,49 +155,101 @@ var bufferPool = sync.Pool{
 // 全局float32切片池（最大支持8KB/2=4096采样点）
 var float32Pool = sync.Pool{}
 
-func getFloat32PoolSlice() []float32 {
-	chunkSize := config.GlobalConfig.Audio.ChunkSize
-	if chunkSize <= 0 {
-		chunkSize = 4096
+func getFloat32PoolSlice(maxFrameSamples int) []float32 {
+	if maxFrameSamples <= 0 {
+		maxFrameSamples = config.Load().Audio.ChunkSize
+	}
+	if maxFrameSamples <= 0 {
+		maxFrameSamples = 4096
 	}
-	return make([]float32, chunkSize)
+	return make([]float32, maxFrameSamples)
 }
 
-// NewManager 创建新的会话管理器
-func NewManager(recognizer *sherpa.OfflineRecognizer, vadPool pool.VADPoolInterface) *Manager {
+// NewManager 创建新的会话管理器，以功能选项方式注入识别引擎、VAD策略等可插拔依赖。
+// WithRecognizerFactory与WithVADStrategy是必需选项，未提供时会panic——这与此前
+// recognizer/vadPool作为构造函数必填参数的语义保持一致，只是改由选项表达
+func NewManager(opts ...ManagerOption) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	idleTimeout := time.Duration(config.Load().Session.IdleTimeoutSeconds) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = 5 * time.Minute
+	}
+	maxSessionAge := time.Duration(config.Load().Session.MaxSessionAgeSeconds) * time.Second
+
 	manager := &Manager{
-		sessions:   make(map[string]*Session),
-		recognizer: recognizer,
-		vadPool:    vadPool,
-		ctx:        ctx,
-		cancel:     cancel,
+		sessions:         make(map[string]*Session),
+		decoderRegistry:  codec.NewFactory(),
+		idleTimeout:      idleTimeout,
+		maxSessionAge:    maxSessionAge,
+		expiryWake:       make(chan struct{}, 1),
+		vadPools:         make(map[pool.VADPoolInterface]struct{}),
+		drainingSessions: make(map[string]*Session),
+		hub:              newHub(),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	for _, opt := range opts {
+		opt(manager)
+	}
+
+	if manager.recognizerFactory == nil {
+		panic("session.NewManager: WithRecognizerFactory is required")
+	}
+	if manager.vadStrategy == nil {
+		panic("session.NewManager: WithVADStrategy is required")
 	}
 
+	if manager.segmentSink != nil && manager.preRollMs > 0 {
+		manager.preRollSamples = manager.preRollMs * config.Load().Audio.SampleRate / 1000
+	}
+
+	go manager.runReaper()
+
 	return manager
 }
 
-// CreateSession 创建新会话
+// CreateSession 创建新会话。recognizer/vadPool在此通过recognizerFactory/vadStrategy
+// 按SessionMeta解析一次并固定到该会话，会话生命周期内不再变化
 func (m *Manager) CreateSession(sessionID string, conn *websocket.Conn) (*Session, error) {
-	// 不在此处分配VAD实例，VADInstance初始化为nil
-	if m.vadPool == nil {
+	meta := SessionMeta{SessionID: sessionID}
+
+	vadPool := m.vadStrategy(meta)
+	if vadPool == nil {
 		return nil, fmt.Errorf("VAD pool is not initialized")
 	}
+	recognizer := m.recognizerFactory(meta)
+	if recognizer == nil {
+		return nil, fmt.Errorf("recognizer is not initialized")
+	}
+
+	m.vadPoolsMu.Lock()
+	m.vadPools[vadPool] = struct{}{}
+	m.vadPoolsMu.Unlock()
 
+	now := time.Now().UnixNano()
 	session := &Session{
-		ID:                sessionID,
-		Conn:              conn,
-		VADInstance:       nil, // 延迟分配
-		LastSeen:          time.Now().UnixNano(),
-		closed:            0,
-		SendQueue:         make(chan interface{}, config.GlobalConfig.Session.SendQueueSize),
-		sendDone:          make(chan struct{}),
-		sendErrCount:      0,
-		lastActivity:      time.Now(),
-		isInSpeech:        false,
-		currentSegment:    nil,
-		silenceFrameCount: 0,
+		ID:             sessionID,
+		Conn:           conn,
+		VADInstance:    nil, // 延迟分配
+		LastSeen:       now,
+		CreatedAt:      now,
+		closed:         0,
+		SendQueue:      make(chan interface{}, config.Load().Session.SendQueueSize),
+		sendDone:       make(chan struct{}),
+		sendErrCount:   0,
+		lastActivity:   time.Now(),
+		isInSpeech:     false,
+		currentSegment: nil,
+		recognizer:     recognizer,
+		vadPool:        vadPool,
+		resumeIssuedAt: now,
+		resumeCh:       make(chan struct{}),
+	}
+
+	if m.speakerManager != nil {
+		session.diarization = newDiarizationState()
 	}
 
 	// 启动发送协程
@@ -120,10 +261,63 @@ func (m *Manager) CreateSession(sessionID string, conn *websocket.Conn) (*Sessio
 
 	atomic.AddInt64(&m.totalSessions, 1)
 	atomic.AddInt64(&m.activeSessions, 1)
+	metrics.ActiveSessions.Inc()
+
+	m.pushExpiry(session, now)
 
 	return session, nil
 }
 
+// ConfigureCodec 设置会话协商到的编解码格式与其原生采样率，通常在WebSocket子协议
+// 协商完成后或收到首条config控制消息时调用；真正的Decoder实例延迟到首次收到
+// 音频帧时才创建，因此本方法调用顺序先于音频帧到达即可生效
+func (m *Manager) ConfigureCodec(sessionID, codecName string, sampleRate int) error {
+	session, exists := m.GetSession(sessionID)
+	if !exists {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.Decoder != nil {
+		return fmt.Errorf("codec already negotiated for session %s", sessionID)
+	}
+	session.CodecName = codecName
+	session.CodecSampleRate = sampleRate
+	return nil
+}
+
+// SetPeerIdentity 记录mTLS握手中客户端证书的Subject/CommonName，供下游speaker/ASR
+// 等handler据此做基于证书身份的鉴权；session不存在时为no-op，因为调用方(ws.HandleWebSocket)
+// 总是在CreateSession成功之后才会拿到非空的证书信息
+func (m *Manager) SetPeerIdentity(sessionID, subject, commonName string) {
+	session, exists := m.GetSession(sessionID)
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.PeerCertSubject = subject
+	session.PeerCertCN = commonName
+}
+
+// JoinRoom 将sessionID对应的会话订阅到roomID房间（通常roomID即为生产会话自身的sessionID）
+func (m *Manager) JoinRoom(sessionID, roomID string) error {
+	session, exists := m.GetSession(sessionID)
+	if !exists {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	m.hub.joinRoom(session, roomID)
+	return nil
+}
+
+// LeaveRoom 取消sessionID对roomID房间的订阅
+func (m *Manager) LeaveRoom(sessionID, roomID string) {
+	m.hub.leaveRoom(sessionID, roomID)
+}
+
 // GetSession 获取会话
 func (m *Manager) GetSession(sessionID string) (*Session, bool) {
 	m.mu.RLock()
@@ -132,23 +326,126 @@ func (m *Manager) GetSession(sessionID string) (*Session, bool) {
 
 	if exists {
 		// 使用原子操作更新LastSeen
-		atomic.StoreInt64(&session.LastSeen, time.Now().UnixNano())
+		now := time.Now().UnixNano()
+		atomic.StoreInt64(&session.LastSeen, now)
+		m.pushExpiry(session, now)
 	}
 
 	return session, exists
 }
 
-// RemoveSession 移除会话
+// RemoveSession 移除会话。未启用会话恢复(resumeSecret为空)时立即销毁，行为与此前一致；
+// 启用时改为先把会话移入drainingSessions保留drainGrace时长，断开的只是底层*websocket.Conn，
+// VAD实例、累积的音频段、声纹分离状态等都原样保留，等待客户端带着resume_token重连时
+// 由TryResume重新绑定；宽限期内始终没有人来Resume才真正调用closeSession销毁
 func (m *Manager) RemoveSession(sessionID string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.sessions, sessionID)
+	m.mu.Unlock()
+
+	atomic.AddInt64(&m.activeSessions, -1)
+	metrics.ActiveSessions.Dec()
 
-	if session, exists := m.sessions[sessionID]; exists {
+	if len(m.resumeSecret) == 0 || m.drainGrace <= 0 {
 		m.closeSession(session)
-		delete(m.sessions, sessionID)
-		atomic.AddInt64(&m.activeSessions, -1)
 		logger.Infof("🗑️  Session removed")
+		return
+	}
+
+	session.mu.Lock()
+	oldConn := session.Conn
+	session.Conn = nil
+	session.draining = true
+	session.mu.Unlock()
+	if oldConn != nil {
+		oldConn.Close()
+	}
+
+	m.drainingMu.Lock()
+	m.drainingSessions[sessionID] = session
+	m.drainingMu.Unlock()
+
+	logger.Infof("💤 Session %s entering %s drain grace period, awaiting resume", sessionID, m.drainGrace)
+
+	time.AfterFunc(m.drainGrace, func() {
+		m.drainingMu.Lock()
+		_, stillDraining := m.drainingSessions[sessionID]
+		delete(m.drainingSessions, sessionID)
+		m.drainingMu.Unlock()
+
+		if stillDraining {
+			logger.Infof("🗑️  Session %s drain grace period elapsed without resume, tearing down", sessionID)
+			m.closeSession(session)
+		}
+	})
+}
+
+// IssueResumeToken 为sessionID签发一个resume_token，供ws.HandleWebSocket在连接确认消息里
+// 下发给客户端；会话恢复功能未启用(resumeSecret为空)或会话不存在时ok为false
+func (m *Manager) IssueResumeToken(sessionID string) (token string, ok bool) {
+	if len(m.resumeSecret) == 0 {
+		return "", false
+	}
+
+	m.mu.RLock()
+	session, exists := m.sessions[sessionID]
+	m.mu.RUnlock()
+	if !exists {
+		return "", false
+	}
+
+	return GenerateResumeToken(m.resumeSecret, sessionID, session.resumeIssuedAt), true
+}
+
+// TryResume 校验resume_token并把一个仍在drain宽限期内的会话重新绑定到新的*websocket.Conn上，
+// 使VAD实例、累积音频段与声纹分离状态得以延续，而不是像普通新连接那样重新分配。
+// token校验失败时会直接销毁该drain中的会话，防止被反复猜测token
+func (m *Manager) TryResume(sessionID, token string, conn *websocket.Conn) (*Session, bool) {
+	if len(m.resumeSecret) == 0 {
+		return nil, false
+	}
+
+	m.drainingMu.Lock()
+	session, ok := m.drainingSessions[sessionID]
+	if ok {
+		delete(m.drainingSessions, sessionID)
+	}
+	m.drainingMu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	if err := VerifyResumeToken(m.resumeSecret, sessionID, token, session.resumeIssuedAt, m.resumeTTL, time.Now()); err != nil {
+		logger.Warnf("Session %s: resume rejected: %v", sessionID, err)
+		m.closeSession(session)
+		return nil, false
 	}
+
+	session.mu.Lock()
+	session.Conn = conn
+	session.draining = false
+	woken := session.resumeCh
+	session.resumeCh = make(chan struct{})
+	session.mu.Unlock()
+	close(woken)
+
+	m.mu.Lock()
+	m.sessions[sessionID] = session
+	m.mu.Unlock()
+
+	atomic.AddInt64(&m.activeSessions, 1)
+	metrics.ActiveSessions.Inc()
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&session.LastSeen, now)
+	m.pushExpiry(session, now)
+
+	logger.Infof("🔁 Session %s resumed on new connection", sessionID)
+	return session, true
 }
 
 // sendLoop 发送循环
@@ -166,12 +463,33 @@ func (s *Session) sendLoop() {
 				return
 			}
 
+			s.mu.RLock()
+			conn := s.Conn
+			resumeCh := s.resumeCh
+			s.mu.RUnlock()
+
+			// 会话处于drain宽限期(Conn已被RemoveSession置空，等待TryResume重新绑定)：
+			// 消息不会丢弃，而是阻塞在这里直到Resume唤醒或会话被彻底销毁
+			if conn == nil {
+				select {
+				case <-resumeCh:
+				case <-s.sendDone:
+					return
+				}
+				s.mu.RLock()
+				conn = s.Conn
+				s.mu.RUnlock()
+				if conn == nil {
+					continue
+				}
+			}
+
 			// 直接写消息，不再设置写超时
-			if err := s.Conn.WriteJSON(msg); err != nil {
+			if err := conn.WriteJSON(msg); err != nil {
 				atomic.AddInt32(&s.sendErrCount, 1)
 				logger.Errorf("Failed to send message to session %s: %v", s.ID, err)
 				// 如果连续错误超过阈值，关闭会话
-				if atomic.LoadInt32(&s.sendErrCount) > int32(config.GlobalConfig.Session.MaxSendErrors) {
+				if atomic.LoadInt32(&s.sendErrCount) > int32(config.Load().Session.MaxSendErrors) {
 					logger.Errorf("Too many send errors for session, closing")
 					atomic.StoreInt32(&s.closed, 1)
 					return
@@ -185,8 +503,9 @@ func (s *Session) sendLoop() {
 	}
 }
 
-// ProcessAudioData 处理音频数据
-func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
+// ProcessAudioData 处理音频数据。ctx是HandleWebSocket里创建的connection根span，
+// 贯穿VAD/识别/说话人识别整条链路，使这些下游调用能挂出自己的子span
+func (m *Manager) ProcessAudioData(ctx context.Context, sessionID string, audioData []byte) error {
 	session, exists := m.GetSession(sessionID)
 	if !exists {
 		logger.Errorf("Session %s not found when processing audio data", sessionID)
@@ -200,7 +519,7 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 
 	// 检查并延迟分配VAD实例
 	if session.VADInstance == nil {
-		vadInstance, err := m.vadPool.Get()
+		vadInstance, err := session.vadPool.Get()
 		if err != nil {
 			logger.Errorf("Failed to get VAD instance for session %s: %v", sessionID, err)
 			return fmt.Errorf("failed to get VAD instance for session %s: %v", sessionID, err)
@@ -210,7 +529,9 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 	}
 
 	// 更新会话活跃时间
-	atomic.StoreInt64(&session.LastSeen, time.Now().UnixNano())
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&session.LastSeen, now)
+	m.pushExpiry(session, now)
 	atomic.AddInt64(&m.totalMessages, 1)
 
 	// 验证输入数据
@@ -219,46 +540,77 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 		return fmt.Errorf("empty audio data")
 	}
 
-	if len(audioData)%2 != 0 {
-		logger.Warnf("Session %s: Audio data length %d is not even (expecting 16-bit samples)", sessionID, len(audioData))
-		return fmt.Errorf("invalid audio data length: %d", len(audioData))
+	// 延迟创建编解码器：握手阶段协商的CodecName/CodecSampleRate此时才真正生效，
+	// 未协商时退化为此前硬编码的pcm_s16le行为
+	if session.Decoder == nil {
+		session.mu.Lock()
+		if session.Decoder == nil {
+			codecName := session.CodecName
+			if codecName == "" {
+				codecName = codec.DefaultCodec
+			}
+			decoder, err := m.decoderRegistry.NewDecoder(codecName, session.CodecSampleRate, config.Load().Audio.SampleRate)
+			if err != nil {
+				session.mu.Unlock()
+				logger.Errorf("Session %s: failed to create %s decoder: %v", sessionID, codecName, err)
+				return fmt.Errorf("failed to create codec decoder: %w", err)
+			}
+			session.Decoder = decoder
+		}
+		session.mu.Unlock()
 	}
 
-	// 转换音频数据
-	numSamples := len(audioData) / 2
-	samples := float32Pool.Get()
+	decoded, err := session.Decoder.Decode(audioData)
+	if err != nil {
+		logger.Warnf("Session %s: codec decode failed: %v", sessionID, err)
+		return fmt.Errorf("codec decode failed: %w", err)
+	}
+	if len(decoded) == 0 {
+		logger.Warnf("Session %s: codec produced no samples from frame", sessionID)
+		return fmt.Errorf("empty audio data")
+	}
+
+	// 转换音频数据：float32Pool按Decoder.MaxFrameSamples()而非固定ChunkSize分配，
+	// 不同编解码器单帧可能产生差异很大的采样点数量（如Opus 60ms帧 vs 原始PCM chunk）
+	numSamples := len(decoded)
+	pooled := float32Pool.Get()
 	var float32Slice []float32
-	if samples == nil {
-		float32Slice = getFloat32PoolSlice()
+	if pooled == nil {
+		float32Slice = getFloat32PoolSlice(session.Decoder.MaxFrameSamples())
 	} else {
-		float32Slice = samples.([]float32)
+		float32Slice = pooled.([]float32)
 	}
 	if cap(float32Slice) < numSamples {
 		float32Slice = make([]float32, numSamples)
 	}
 	float32Slice = float32Slice[:numSamples]
+	copy(float32Slice, decoded)
 	defer float32Pool.Put(float32Slice)
-	normalizeFactor := config.GlobalConfig.Audio.NormalizeFactor
-	for i := 0; i < numSamples; i++ {
-		sample := int16(audioData[i*2]) | int16(audioData[i*2+1])<<8
-		float32Slice[i] = float32(sample) / normalizeFactor
-	}
 
-	logger.Debugf("Session %s: Converted %d bytes to %d float32 samples", sessionID, len(audioData), numSamples)
+	logger.Debugf("Session %s: Decoded %d bytes to %d float32 samples via %s", sessionID, len(audioData), numSamples, session.CodecName)
+	metrics.SessionAudioBytesTotal.Add(float64(len(audioData)))
+
+	vadCtx, vadSpan := tracing.Tracer().Start(ctx, "vad.process")
+	vadSpan.SetAttributes(attribute.String("vad_type", session.VADInstance.GetType()))
+	defer vadSpan.End()
 
 	// 根据VAD类型处理
 	switch session.VADInstance.GetType() {
 	case pool.SILERO_TYPE:
-		return m.processSileroVAD(session, sessionID, float32Slice)
-	// case pool.TEN_VAD_TYPE: // Disabled for macOS
-	// 	return m.processTenVAD(session, sessionID, float32Slice)
+		return m.processSileroVAD(vadCtx, session, sessionID, float32Slice)
+	case pool.TEN_VAD_TYPE:
+		return m.processTenVAD(vadCtx, session, sessionID, float32Slice)
+	case pool.WEBRTC_TYPE:
+		return m.processWebRTCVAD(vadCtx, session, sessionID, float32Slice)
+	case pool.ENERGY_TYPE:
+		return m.processEnergyVAD(vadCtx, session, sessionID, float32Slice)
 	default:
 		return fmt.Errorf("unsupported VAD type: %s", session.VADInstance.GetType())
 	}
 }
 
 // processSileroVAD 处理Silero VAD
-func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Slice []float32) error {
+func (m *Manager) processSileroVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32) error {
 	// 类型断言获取Silero VAD实例
 	sileroInstance, ok := session.VADInstance.(*pool.SileroVADInstance)
 	if !ok {
@@ -266,7 +618,7 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 	}
 
 	// VAD检测 - 使用响应超时配置
-	vadTimeout := time.Duration(config.GlobalConfig.Response.Timeout) * time.Second
+	vadTimeout := time.Duration(config.Load().Response.Timeout) * time.Second
 	vadCtx, vadCancel := context.WithTimeout(context.Background(), vadTimeout)
 	defer vadCancel()
 
@@ -286,10 +638,50 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 		return fmt.Errorf("VAD processing timeout")
 	}
 
+	sampleRate := config.Load().Audio.SampleRate
+
+	// preRoll持续滚动保留最近preRollSamples个采样点，仅在配置了segmentSink时才维护，
+	// 必须在判断speech_start之前更新，使其反映"这一帧之前"的音频
+	if m.segmentSink != nil && m.preRollSamples > 0 {
+		session.preRoll = append(session.preRoll, float32Slice...)
+		if len(session.preRoll) > m.preRollSamples {
+			session.preRoll = session.preRoll[len(session.preRoll)-m.preRollSamples:]
+		}
+	}
+
+	// speech_start端点事件：以"自上次speech_end以来首次有样本进入累积缓冲区"作为近似信号
+	// （sherpa的VAD实例只暴露已完成语音段的队列，无法直接拿到"当前是否处于语音中"的状态）
+	if !session.isInSpeech && len(float32Slice) > 0 {
+		session.isInSpeech = true
+		segID := atomic.AddInt64(&session.segmentID, 1)
+
+		session.segMu.Lock()
+		prevDecode := session.activeDecode
+		if prevDecode != nil && config.Load().Response.EnableBargeIn {
+			prevDecode.cancel()
+			session.activeDecode = nil
+		}
+		session.segMu.Unlock()
+
+		if prevDecode != nil && config.Load().Response.EnableBargeIn {
+			m.emitLifecycleEvent(session, sessionID, "barge_in", prevDecode.id)
+		}
+		m.emitLifecycleEvent(session, sessionID, "speech_start", segID)
+
+		if m.segmentSink != nil {
+			session.pendingPreRoll = append([]float32(nil), session.preRoll...)
+		}
+	}
+	session.currentSegment = append(session.currentSegment, float32Slice...)
+
+	// 按PartialIntervalMs节流，对当前累积的buffer做一次低成本解码并推送partial结果
+	if session.isInSpeech && config.Load().Response.EmitPartials {
+		m.maybeEmitPartial(session, sessionID, sampleRate)
+	}
+
 	// 处理语音段
 	segmentCount := 0
 	var speechSegments [][]float32
-	sampleRate := config.GlobalConfig.Audio.SampleRate
 
 	// 收集所有有效的语音段
 	for !sileroInstance.VAD.IsEmpty() {
@@ -304,6 +696,13 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 				return fmt.Errorf("session %s closed during processing", sessionID)
 			}
 
+			// speech_end端点事件：VAD完成了一个语音段即代表此次发声结束，
+			// 不论该段最终是否因过短被丢弃；重置累积缓冲区等待下一次speech_start
+			segID := atomic.LoadInt64(&session.segmentID)
+			session.isInSpeech = false
+			session.currentSegment = session.currentSegment[:0]
+			m.emitLifecycleEvent(session, sessionID, "speech_end", segID)
+
 			// 验证音频数据
 			if len(segment.Samples) == 0 {
 				logger.Warnf("Session %s: Speech segment %d has no samples", sessionID, segmentCount)
@@ -312,20 +711,24 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 
 			// 音频时长检查
 			duration := float64(len(segment.Samples)) / float64(sampleRate)
-			minSpeechDuration := float64(config.GlobalConfig.VAD.SileroVAD.MinSpeechDuration)
+			minSpeechDuration := float64(config.Load().VAD.SileroVAD.MinSpeechDuration)
 			if duration < minSpeechDuration {
 				logger.Debugf("Session %s: Skipping short segment %d (%.2fs < %.2fs)", sessionID, segmentCount, duration, minSpeechDuration)
 				continue
 			}
 
 			// 检查最大时长
-			maxDuration := float64(config.GlobalConfig.VAD.SileroVAD.MaxSpeechDuration)
+			maxDuration := float64(config.Load().VAD.SileroVAD.MaxSpeechDuration)
 			if duration > maxDuration {
 				logger.Warnf("Session %s: Segment %d too long (%.2fs > %.2fs), truncating", sessionID, segmentCount, duration, maxDuration)
 				maxSamples := int(maxDuration * float64(sampleRate))
 				segment.Samples = segment.Samples[:maxSamples]
 			}
 
+			if m.segmentPostProcessor != nil {
+				segment.Samples = m.segmentPostProcessor(segment.Samples)
+			}
+
 			speechSegments = append(speechSegments, segment.Samples)
 			logger.Debugf("Session %s: Collected segment %d with %d samples (%.2fs)", sessionID, segmentCount, len(segment.Samples), duration)
 		} else {
@@ -333,105 +736,274 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 		}
 	}
 
-	// 处理收集到的语音段
+	m.dispatchSpeechSegments(ctx, session, sessionID, speechSegments, sampleRate)
+
+	return nil
+}
+
+// dispatchSpeechSegments 把某个VAD后端收集到的完整语音段推进说话人归属判定、录音sink和
+// 识别解码，与VAD后端本身无关（只依赖[][]float32+sampleRate），使Silero/WebRTC/Energy/TEN-VAD
+// 共用同一套后处理，下游不需要为每种后端单独实现一遍
+func (m *Manager) dispatchSpeechSegments(ctx context.Context, session *Session, sessionID string, speechSegments [][]float32, sampleRate int) {
 	for i, samples := range speechSegments {
 		// 提交识别任务
 		taskID := fmt.Sprintf("%s_%d_%d", sessionID, time.Now().UnixNano(), i)
-		go func(samples []float32, sampleRate int, sessionID string, taskID string) {
-			stream := sherpa.NewOfflineStream(m.recognizer)
-			defer sherpa.DeleteOfflineStream(stream)
+
+		// 说话人归属判定放在goroutine外同步执行，保证同一会话内turn_id按语音段顺序递增
+		_, spkSpan := tracing.Tracer().Start(ctx, "speaker.identify")
+		speakerLabel, turnID := m.identifySegmentSpeaker(session, samples, sampleRate)
+		spkSpan.SetAttributes(attribute.String("speaker_label", speakerLabel))
+		spkSpan.End()
+
+		if m.segmentSink != nil {
+			var preRoll []float32
+			if i == 0 {
+				preRoll = session.pendingPreRoll
+				session.pendingPreRoll = nil
+			}
+			m.segmentSink.WriteSegment(recorder.SegmentEvent{
+				SessionID:  sessionID,
+				SpeakerID:  speakerLabel,
+				Samples:    samples,
+				SampleRate: sampleRate,
+				PreRoll:    preRoll,
+			})
+		}
+
+		segID := atomic.LoadInt64(&session.segmentID)
+		decodeCtx, decodeCancel := context.WithCancel(m.ctx)
+
+		session.segMu.Lock()
+		session.activeDecode = &segmentDecode{id: segID, cancel: decodeCancel}
+		session.segMu.Unlock()
+
+		// decodeSpan挂在ctx(connection根span)之下而不是decodeCtx：decodeCtx仅用于
+		// barge-in取消，解码本身的可观测性不应该因为这段话被打断就断在一半
+		_, decodeSpan := tracing.Tracer().Start(ctx, "recognizer.decode")
+
+		go func(samples []float32, sampleRate int, sessionID string, taskID string, segID int64, decodeCtx context.Context, decodeCancel context.CancelFunc) {
+			defer decodeCancel()
+			defer decodeSpan.End()
+
+			decodeStart := time.Now()
+			stream := session.recognizer.NewStream()
+			defer stream.Close()
 			stream.AcceptWaveform(sampleRate, samples)
-			m.recognizer.Decode(stream)
+			session.recognizer.Decode(stream)
+			metrics.RecognizerDecodeDuration.Observe(time.Since(decodeStart).Seconds())
 			result := stream.GetResult()
+
+			session.segMu.Lock()
+			if session.activeDecode != nil && session.activeDecode.id == segID {
+				session.activeDecode = nil
+			}
+			session.segMu.Unlock()
+
+			if decodeCtx.Err() != nil {
+				// 该语音段已被更新的speech_start取消(barge-in)，其结果已过时，丢弃
+				logger.Debugf("Session %s: discarding stale decode result for segment %d (barge-in)", sessionID, segID)
+				return
+			}
+
 			if result != nil {
-				m.handleRecognitionResult(sessionID, result.Text, nil)
+				m.handleRecognitionResult(sessionID, result.Text, nil, speakerLabel, turnID, segID)
 			} else {
-				m.handleRecognitionResult(sessionID, "", fmt.Errorf("recognition failed"))
+				m.handleRecognitionResult(sessionID, "", fmt.Errorf("recognition failed"), speakerLabel, turnID, segID)
 			}
-		}(samples, sampleRate, sessionID, taskID)
+		}(samples, sampleRate, sessionID, taskID, segID, decodeCtx, decodeCancel)
+	}
+}
+
+// genericVADInstance 是webrtc_vad/energy_vad/ten_vad共用的最小接口：纯Go实现,
+// AcceptWaveform同步执行不会阻塞，语音段队列形状与sherpa.VoiceActivityDetector一致，
+// 因此可以复用同一套processGenericVAD，不必像Silero那样为cgo调用包一层超时保护
+type genericVADInstance interface {
+	AcceptWaveform(samples []float32)
+	IsEmpty() bool
+	Front() *pool.VADSegment
+	Pop()
+}
+
+// processGenericVAD 处理不依赖ONNX模型的VAD后端（webrtc_vad/energy_vad/ten_vad）：
+// 语音段的时长过滤已经在各自实例的flushSegment里做过（ten_vad只丢弃过短的段，不按最长时长截断），这里只负责端点事件、pre-roll、
+// partial结果节流和把收集到的语音段交给dispatchSpeechSegments，与processSileroVAD
+// 共享完全相同的下游行为
+func (m *Manager) processGenericVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32, instance genericVADInstance) error {
+	instance.AcceptWaveform(float32Slice)
+
+	sampleRate := config.Load().Audio.SampleRate
+
+	// preRoll持续滚动保留最近preRollSamples个采样点，仅在配置了segmentSink时才维护，
+	// 必须在判断speech_start之前更新，使其反映"这一帧之前"的音频
+	if m.segmentSink != nil && m.preRollSamples > 0 {
+		session.preRoll = append(session.preRoll, float32Slice...)
+		if len(session.preRoll) > m.preRollSamples {
+			session.preRoll = session.preRoll[len(session.preRoll)-m.preRollSamples:]
+		}
 	}
 
+	// speech_start端点事件：语义与processSileroVAD一致，见那里的注释
+	if !session.isInSpeech && len(float32Slice) > 0 {
+		session.isInSpeech = true
+		segID := atomic.AddInt64(&session.segmentID, 1)
+
+		session.segMu.Lock()
+		prevDecode := session.activeDecode
+		if prevDecode != nil && config.Load().Response.EnableBargeIn {
+			prevDecode.cancel()
+			session.activeDecode = nil
+		}
+		session.segMu.Unlock()
+
+		if prevDecode != nil && config.Load().Response.EnableBargeIn {
+			m.emitLifecycleEvent(session, sessionID, "barge_in", prevDecode.id)
+		}
+		m.emitLifecycleEvent(session, sessionID, "speech_start", segID)
+
+		if m.segmentSink != nil {
+			session.pendingPreRoll = append([]float32(nil), session.preRoll...)
+		}
+	}
+	session.currentSegment = append(session.currentSegment, float32Slice...)
+
+	if session.isInSpeech && config.Load().Response.EmitPartials {
+		m.maybeEmitPartial(session, sessionID, sampleRate)
+	}
+
+	segmentCount := 0
+	var speechSegments [][]float32
+
+	for !instance.IsEmpty() {
+		segment := instance.Front()
+		instance.Pop()
+		segmentCount++
+
+		if segment == nil || len(segment.Samples) == 0 {
+			logger.Warnf("Session %s: Empty or null speech segment %d", sessionID, segmentCount)
+			continue
+		}
+
+		if atomic.LoadInt32(&session.closed) == 1 {
+			logger.Warnf("Session %s closed during speech segment processing", sessionID)
+			return fmt.Errorf("session %s closed during processing", sessionID)
+		}
+
+		// speech_end端点事件：该后端判定一个语音段结束即代表此次发声结束，
+		// 不论该段最终是否因过短被丢弃；重置累积缓冲区等待下一次speech_start
+		segID := atomic.LoadInt64(&session.segmentID)
+		session.isInSpeech = false
+		session.currentSegment = session.currentSegment[:0]
+		m.emitLifecycleEvent(session, sessionID, "speech_end", segID)
+
+		if m.segmentPostProcessor != nil {
+			segment.Samples = m.segmentPostProcessor(segment.Samples)
+		}
+
+		duration := float64(len(segment.Samples)) / float64(sampleRate)
+		speechSegments = append(speechSegments, segment.Samples)
+		logger.Debugf("Session %s: Collected segment %d with %d samples (%.2fs)", sessionID, segmentCount, len(segment.Samples), duration)
+	}
+
+	m.dispatchSpeechSegments(ctx, session, sessionID, speechSegments, sampleRate)
+
 	return nil
 }
 
-// processTenVAD 处理TEN-VAD - Disabled for macOS
-// func (m *Manager) processTenVAD(session *Session, sessionID string, float32Slice []float32) error {
-// 	// 类型断言获取TEN-VAD实例
-// 	tenVADInstance, ok := session.VADInstance.(*pool.TenVADInstance)
-// 	if !ok {
-// 		return fmt.Errorf("invalid TEN-VAD instance type")
-// 	}
-//
-// 	hopSize := config.GlobalConfig.VAD.TenVAD.HopSize
-// 	minSpeechFrames := config.GlobalConfig.VAD.TenVAD.MinSpeechFrames
-// 	maxSilenceFrames := config.GlobalConfig.VAD.TenVAD.MaxSilenceFrames
-//
-// 	// 分帧处理
-// 	for i := 0; i < len(float32Slice); i += hopSize {
-// 		end := i + hopSize
-// 		if end > len(float32Slice) {
-// 			end = len(float32Slice)
-// 		}
-// 		frame := float32Slice[i:end]
-// 		int16Frame := make([]int16, len(frame))
-// 		for j, f := range frame {
-// 			int16Frame[j] = int16(f * 32768)
-// 		}
-// 		_, flag, err := pool.GetInstance().ProcessAudio(tenVADInstance.Handle, int16Frame)
-// 		if err != nil {
-// 			return fmt.Errorf("TEN-VAD ProcessAudio error: %v", err)
-// 		}
-//
-// 		if flag == 1 {
-// 			if !session.isInSpeech {
-// 				logger.Debugf("Session %s: Speech started", sessionID)
-// 				session.isInSpeech = true
-// 				session.currentSegment = make([]float32, 0)
-// 				session.silenceFrameCount = 0
-// 			}
-// 			session.currentSegment = append(session.currentSegment, frame...)
-// 			session.silenceFrameCount = 0 // 重置静音计数
-// 		} else {
-// 			if session.isInSpeech {
-// 				session.silenceFrameCount++
-// 				session.currentSegment = append(session.currentSegment, frame...)
-// 				if session.silenceFrameCount >= maxSilenceFrames {
-// 					frameCount := len(session.currentSegment) / hopSize
-// 					if frameCount >= minSpeechFrames {
-// 						logger.Debugf("Session %s: Speech segment completed with %d samples (%d frames)", sessionID, len(session.currentSegment), frameCount)
-// 						duration := float64(len(session.currentSegment)) / float64(config.GlobalConfig.Audio.SampleRate)
-// 						logger.Infof("ASR segment length: %.2fs, samples: %d", duration, len(session.currentSegment))
-// 						taskID := fmt.Sprintf("%s_%d", sessionID, time.Now().UnixNano())
-// 						segmentCopy := make([]float32, len(session.currentSegment))
-// 						copy(segmentCopy, session.currentSegment)
-// 						go func(segment []float32, sessionID string, taskID string) {
-// 							stream := sherpa.NewOfflineStream(m.recognizer)
-// 							defer sherpa.DeleteOfflineStream(stream)
-// 							stream.AcceptWaveform(config.GlobalConfig.Audio.SampleRate, segment)
-// 							m.recognizer.Decode(stream)
-// 							result := stream.GetResult()
-// 							if result != nil {
-// 								m.handleRecognitionResult(sessionID, result.Text, nil)
-// 							} else {
-// 								m.handleRecognitionResult(sessionID, "", fmt.Errorf("recognition failed"))
-// 							}
-// 						}(segmentCopy, sessionID, taskID)
-// 					} else {
-// 						logger.Debugf("Session %s: Speech segment too short (%d frames), discarding", sessionID, frameCount)
-// 					}
-// 					session.isInSpeech = false
-// 					session.silenceFrameCount = 0
-// 					session.currentSegment = nil
-// 				}
-// 			}
-// 		}
-// 	}
-//
-// 	return nil
-// }
+// processWebRTCVAD 处理纯Go WebRTC风格VAD
+func (m *Manager) processWebRTCVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32) error {
+	instance, ok := session.VADInstance.(*pool.WebRTCVADInstance)
+	if !ok {
+		return fmt.Errorf("invalid WebRTC-style VAD instance type")
+	}
+	return m.processGenericVAD(ctx, session, sessionID, float32Slice, instance)
+}
+
+// processEnergyVAD 处理RMS/ZCR能量VAD
+func (m *Manager) processEnergyVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32) error {
+	instance, ok := session.VADInstance.(*pool.EnergyVADInstance)
+	if !ok {
+		return fmt.Errorf("invalid Energy VAD instance type")
+	}
+	return m.processGenericVAD(ctx, session, sessionID, float32Slice, instance)
+}
+
+// emitLifecycleEvent 向客户端推送端点/打断事件(speech_start、speech_end、barge_in)，
+// 非阻塞发送，队列已满时丢弃并记录告警，与其他SendQueue写入路径保持一致
+func (m *Manager) emitLifecycleEvent(session *Session, sessionID string, eventType string, segID int64) {
+	event := map[string]interface{}{
+		"type":       eventType,
+		"segment_id": segID,
+		"timestamp":  time.Now().UnixMilli(),
+	}
+	select {
+	case session.SendQueue <- event:
+	default:
+		logger.Warnf("Session %s send queue is full, dropping %s event", sessionID, eventType)
+	}
+}
+
+// maybeEmitPartial 按PartialIntervalMs节流，对当前累积中的语音段做一次低成本解码，
+// 推送partial识别结果供客户端提前展示；stability按已累积时长相对MaxSpeechDuration估算，
+// 仅作粗略指标，不保证与最终识别结果一致
+func (m *Manager) maybeEmitPartial(session *Session, sessionID string, sampleRate int) {
+	intervalMs := config.Load().Response.PartialIntervalMs
+	if intervalMs <= 0 {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&session.lastPartialAt)
+	if now-last < int64(intervalMs)*int64(time.Millisecond) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&session.lastPartialAt, last, now) {
+		return
+	}
+
+	samples := make([]float32, len(session.currentSegment))
+	copy(samples, session.currentSegment)
+	if len(samples) == 0 {
+		return
+	}
+	segID := atomic.LoadInt64(&session.segmentID)
+
+	go func(samples []float32, sampleRate int, segID int64) {
+		decodeStart := time.Now()
+		stream := session.recognizer.NewStream()
+		defer stream.Close()
+		stream.AcceptWaveform(sampleRate, samples)
+		session.recognizer.Decode(stream)
+		metrics.RecognizerDecodeDuration.Observe(time.Since(decodeStart).Seconds())
+		result := stream.GetResult()
+		if result == nil || result.Text == "" {
+			return
+		}
+
+		maxDuration := float64(config.Load().VAD.SileroVAD.MaxSpeechDuration)
+		stability := 0.0
+		if maxDuration > 0 {
+			stability = float64(len(samples)) / float64(sampleRate) / maxDuration
+			if stability > 1 {
+				stability = 1
+			}
+		}
+
+		select {
+		case session.SendQueue <- map[string]interface{}{
+			"type":       "partial",
+			"text":       result.Text,
+			"stability":  stability,
+			"segment_id": segID,
+			"timestamp":  time.Now().UnixMilli(),
+		}:
+		default:
+			logger.Warnf("Session %s send queue is full, dropping partial result", sessionID)
+		}
+	}(samples, sampleRate, segID)
+}
 
 // handleRecognitionResult 处理识别结果
-func (m *Manager) handleRecognitionResult(sessionID, result string, err error) {
+func (m *Manager) handleRecognitionResult(sessionID, result string, err error, speakerLabel string, turnID int, segID int64) {
 	session, exists := m.GetSession(sessionID)
 	if !exists {
 		logger.Warnf("Session %s not found when handling recognition result, session may have been closed", sessionID)
@@ -447,9 +1019,15 @@ func (m *Manager) handleRecognitionResult(sessionID, result string, err error) {
 	// 只在err为nil且result非空时返回识别结果
 	if err == nil && len(result) > 0 {
 		response := map[string]interface{}{
-			"type":      "final",
-			"text":      result,
-			"timestamp": time.Now().UnixMilli(),
+			"type":       "final",
+			"text":       result,
+			"segment_id": segID,
+			"timestamp":  time.Now().UnixMilli(),
+		}
+		if speakerLabel != "" {
+			response["speaker"] = speakerLabel
+			response["turn_id"] = turnID
+			session.diarization.recordTurn(turnID, speakerLabel, result)
 		}
 		select {
 		case session.SendQueue <- response:
@@ -457,6 +1035,10 @@ func (m *Manager) handleRecognitionResult(sessionID, result string, err error) {
 		default:
 			logger.Warnf("Session %s send queue is full, dropping recognition result", sessionID)
 		}
+
+		if room, ok := m.hub.getRoom(sessionID); ok {
+			room.broadcast(response)
+		}
 		return
 	}
 
@@ -470,6 +1052,11 @@ func (m *Manager) handleRecognitionResult(sessionID, result string, err error) {
 // closeSession 关闭会话
 func (m *Manager) closeSession(session *Session) {
 	if atomic.CompareAndSwapInt32(&session.closed, 0, 1) {
+		// 从所有已订阅的房间中移除该会话，并在该会话自身是生产者时销毁其房间；
+		// 加锁顺序固定为 Hub.mu -> Room.mu，在Manager.mu之后获取，避免死锁
+		m.hub.removeSessionEverywhere(session.ID)
+		m.hub.closeRoomIfProducer(session.ID)
+
 		// 关闭发送通道
 		close(session.sendDone)
 		// 清空发送队列
@@ -478,29 +1065,46 @@ func (m *Manager) closeSession(session *Session) {
 		}
 
 		// 归还VAD实例到池中
-		if session.VADInstance != nil && m.vadPool != nil {
-			m.vadPool.Put(session.VADInstance)
+		if session.VADInstance != nil && session.vadPool != nil {
+			session.vadPool.Put(session.VADInstance)
 			session.VADInstance = nil
 			logger.Infof("🔄 Returned VAD instance to pool for session %s", session.ID)
 		}
 
+		if m.segmentSink != nil {
+			m.segmentSink.CloseSession(session.ID)
+		}
+
 		if session.Conn != nil {
 			session.Conn.Close()
 		}
 	}
 }
 
+// ActiveSessionCount 返回当前在管理的会话数，供/readyz据此判断是否已达到
+// server.max_connections配置的会话上限
+func (m *Manager) ActiveSessionCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
 // GetStats 获取管理器统计信息 - 增强版本
 func (m *Manager) GetStats() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// 获取资源池统计
-	var poolStats map[string]interface{}
-	if m.vadPool != nil {
-		poolStats = m.vadPool.GetStats()
-	} else {
-		poolStats = map[string]interface{}{"status": "not_initialized"}
+	// 获取资源池统计：vadStrategy可能按会话路由到多个不同的池，这里汇总所有已见过的池
+	m.vadPoolsMu.Lock()
+	poolStats := make(map[string]interface{}, len(m.vadPools))
+	i := 0
+	for vadPool := range m.vadPools {
+		poolStats[fmt.Sprintf("pool_%d", i)] = vadPool.GetStats()
+		i++
+	}
+	m.vadPoolsMu.Unlock()
+	if len(poolStats) == 0 {
+		poolStats["status"] = "not_initialized"
 	}
 
 	return map[string]interface{}{
@@ -508,7 +1112,10 @@ func (m *Manager) GetStats() map[string]interface{} {
 		"active_sessions":  atomic.LoadInt64(&m.activeSessions),
 		"total_messages":   atomic.LoadInt64(&m.totalMessages),
 		"current_sessions": len(m.sessions),
+		"expired_sessions": atomic.LoadInt64(&m.expiredSessions),
+		"next_expiry_ns":   atomic.LoadInt64(&m.nextExpiryNs),
 		"pool_stats":       poolStats,
+		"rooms":            m.hub.stats(),
 	}
 }
 
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"asr_server/internal/asr"
+	"asr_server/internal/codec"
+	"asr_server/internal/pool"
+	"asr_server/internal/recorder"
+	"asr_server/internal/speaker"
+)
+
+// SessionMeta 描述创建会话时已知的上下文信息，供RecognizerFactory/VADStrategy据此
+// 路由到不同的引擎或配置（如按语言选择识别模型，或将部分会话路由到另一套VAD做A/B测试）
+type SessionMeta struct {
+	SessionID string
+	Language  string
+}
+
+// ManagerOption 以函数式选项配置Manager，替代此前把recognizer/vadPool写死在
+// 构造函数签名里的做法，从而为替换识别引擎、VAD策略、编解码器注册表等提供扩展点
+type ManagerOption func(*Manager)
+
+// WithRecognizerFactory 按SessionMeta选择识别引擎；未设置时NewManager会panic
+// （识别引擎是会话的必需依赖，与此前recognizer参数不可省略保持一致）
+func WithRecognizerFactory(factory func(SessionMeta) asr.Recognizer) ManagerOption {
+	return func(m *Manager) { m.recognizerFactory = factory }
+}
+
+// WithVADStrategy 按SessionMeta选择VAD池；未设置时NewManager会panic
+func WithVADStrategy(strategy func(SessionMeta) pool.VADPoolInterface) ManagerOption {
+	return func(m *Manager) { m.vadStrategy = strategy }
+}
+
+// WithDecoderRegistry 自定义音频编解码器工厂，未设置时使用codec包内置的默认工厂
+func WithDecoderRegistry(registry *codec.Factory) ManagerOption {
+	return func(m *Manager) { m.decoderRegistry = registry }
+}
+
+// WithMetricsSink 注入Prometheus Registerer供后续注册会话相关指标；
+// 本身不注册任何collector，具体指标由使用方按需注册
+func WithMetricsSink(sink prometheus.Registerer) ManagerOption {
+	return func(m *Manager) { m.metricsSink = sink }
+}
+
+// WithSegmentPostProcessor 在语音段送去识别前做一次后处理（如增益、直流偏置消除、归一化），
+// 默认不做任何处理
+func WithSegmentPostProcessor(proc func([]float32) []float32) ManagerOption {
+	return func(m *Manager) { m.segmentPostProcessor = proc }
+}
+
+// WithSpeakerManager 注入声纹管理器以启用实时说话人分离，不设置则不启用
+func WithSpeakerManager(speakerManager *speaker.Manager) ManagerOption {
+	return func(m *Manager) { m.speakerManager = speakerManager }
+}
+
+// WithSegmentSink 注入录音SegmentSink，每个VAD语音段完成说话人归属判定后都会推送给它；
+// preRollMs用于按Audio.SampleRate换算出speech_start之前保留的采样点数，不设置则不启用录音
+func WithSegmentSink(sink recorder.SegmentSink, preRollMs int) ManagerOption {
+	return func(m *Manager) {
+		m.segmentSink = sink
+		m.preRollMs = preRollMs
+	}
+}
+
+// WithResumption 启用WebSocket重连复用：secret为空表示整个功能禁用，RemoveSession退化为
+// 立即销毁（此前的行为）；secret非空时，ttl限制resume_token的有效期，gracePeriod控制
+// RemoveSession后会话在内存里保留多久等待客户端带着有效token重连，超过该窗口仍未恢复
+// 则按正常流程销毁（归还VAD实例、关闭录音分段等）
+func WithResumption(secret []byte, ttl, gracePeriod time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.resumeSecret = secret
+		m.resumeTTL = ttl
+		m.drainGrace = gracePeriod
+	}
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+
+	"asr_server/internal/logger"
+	"asr_server/internal/metrics"
+)
+
+// expiryEntry 会话过期堆中的一项。lastSeenSnapshot 记录生成该条目时session的LastSeen，
+// 弹出时若与session当前LastSeen不一致，说明会话在此期间被刷新过，该条目已过时应被丢弃，
+// 真正的到期判定以最新推入的条目为准。
+type expiryEntry struct {
+	sessionID        string
+	lastSeenSnapshot int64
+	expiresAt        int64 // UnixNano
+}
+
+// expiryHeap 按expiresAt排序的小顶堆，peek/pop均为O(log n)
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int            { return len(h) }
+func (h expiryHeap) Less(i, j int) bool  { return h[i].expiresAt < h[j].expiresAt }
+func (h expiryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(*expiryEntry)) }
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// pushExpiry 为session计算新的到期时间并推入堆，同时用一次非阻塞唤醒通知reaper重新计算休眠时长。
+// 到期时间取 "空闲超时" 与 "硬上限(MaxSessionAge)" 中较早的一个；MaxSessionAge<=0表示不设硬上限。
+func (m *Manager) pushExpiry(session *Session, lastSeen int64) {
+	expiresAt := lastSeen + m.idleTimeout.Nanoseconds()
+	if m.maxSessionAge > 0 {
+		if hardExpiry := session.CreatedAt + m.maxSessionAge.Nanoseconds(); hardExpiry < expiresAt {
+			expiresAt = hardExpiry
+		}
+	}
+
+	m.expiryMu.Lock()
+	heap.Push(&m.expiryHeap, &expiryEntry{
+		sessionID:        session.ID,
+		lastSeenSnapshot: lastSeen,
+		expiresAt:        expiresAt,
+	})
+	m.expiryMu.Unlock()
+
+	select {
+	case m.expiryWake <- struct{}{}:
+	default:
+	}
+}
+
+// runReaper 驱动过期回收的后台goroutine：休眠至堆顶到期时间，醒来后批量回收已过期会话，
+// 并对每个出堆条目与会话当前LastSeen核对，跳过已被刷新的陈旧条目
+func (m *Manager) runReaper() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		sleep := m.nextSleepDuration()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(sleep)
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.expiryWake:
+			continue
+		case <-timer.C:
+			m.reapExpired()
+		}
+	}
+}
+
+// nextSleepDuration 返回reaper应休眠的时长：堆为空时休眠较长的默认间隔，
+// 否则休眠到堆顶条目的到期时间（不早于0）
+func (m *Manager) nextSleepDuration() time.Duration {
+	m.expiryMu.Lock()
+	defer m.expiryMu.Unlock()
+
+	if len(m.expiryHeap) == 0 {
+		atomic.StoreInt64(&m.nextExpiryNs, 0)
+		return time.Minute
+	}
+
+	expiresAt := m.expiryHeap[0].expiresAt
+	atomic.StoreInt64(&m.nextExpiryNs, expiresAt)
+
+	d := time.Duration(expiresAt - time.Now().UnixNano())
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// reapExpired 弹出所有已到期的堆顶条目，对仍然有效（未被刷新）的条目关闭对应会话
+func (m *Manager) reapExpired() {
+	now := time.Now().UnixNano()
+
+	var toClose []*expiryEntry
+	m.expiryMu.Lock()
+	for len(m.expiryHeap) > 0 && m.expiryHeap[0].expiresAt <= now {
+		entry := heap.Pop(&m.expiryHeap).(*expiryEntry)
+
+		m.mu.RLock()
+		session, exists := m.sessions[entry.sessionID]
+		m.mu.RUnlock()
+
+		if !exists {
+			continue
+		}
+		if atomic.LoadInt64(&session.LastSeen) != entry.lastSeenSnapshot {
+			// 会话在此条目生成后被刷新过，真正的到期判定交给后续推入的新条目
+			continue
+		}
+
+		toClose = append(toClose, entry)
+	}
+	m.expiryMu.Unlock()
+
+	for _, entry := range toClose {
+		m.mu.Lock()
+		session, exists := m.sessions[entry.sessionID]
+		if exists && atomic.LoadInt64(&session.LastSeen) == entry.lastSeenSnapshot {
+			// 两次加锁之间（第一循环释放expiryMu到这里重新获取m.mu）会话仍可能被
+			// 并发的ProcessAudioData/GetSession刷新过，这里必须再核对一次LastSeen，
+			// 否则会把一个在此窗口期内变为活跃的会话误杀
+			m.closeSession(session)
+			delete(m.sessions, entry.sessionID)
+			atomic.AddInt64(&m.activeSessions, -1)
+			metrics.ActiveSessions.Dec()
+			atomic.AddInt64(&m.expiredSessions, 1)
+			logger.Infof("⏱️  Session expired and reaped, session_id=%s", entry.sessionID)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"asr_server/internal/asr"
+	"asr_server/internal/logger"
+	"asr_server/internal/pool"
+)
+
+// noopVADPool 是测试专用的最小VADPoolInterface实现：reaper相关测试不会触达VAD处理，
+// 只需要CreateSession的非空校验能通过
+type noopVADPool struct{}
+
+func (noopVADPool) Initialize() error                       { return nil }
+func (noopVADPool) Get() (pool.VADInstanceInterface, error) { return nil, nil }
+func (noopVADPool) Put(pool.VADInstanceInterface)           {}
+func (noopVADPool) GetStats() map[string]interface{}        { return nil }
+func (noopVADPool) Shutdown()                               {}
+
+// noopStream/noopRecognizer 同理，仅用于满足asr.Recognizer的非空校验
+type noopStream struct{}
+
+func (noopStream) AcceptWaveform(int, []float32) {}
+func (noopStream) GetResult() *asr.Result        { return nil }
+func (noopStream) Close()                        {}
+
+type noopRecognizer struct{}
+
+func (noopRecognizer) NewStream() asr.Stream { return noopStream{} }
+func (noopRecognizer) Decode(asr.Stream)     {}
+func (noopRecognizer) Close()                {}
+
+func TestMain(m *testing.M) {
+	// reapExpired/closeSession等路径会调用logger.Infof，未初始化的logger.Logger是nil
+	// slog.Logger会在首次调用时panic
+	logger.InitLoggerFromConfig(logger.LoggingConfig{Level: "error", Format: "text", Output: "console"})
+	os.Exit(m.Run())
+}
+
+// newTestManager创建一个不依赖真实识别引擎/VAD池的Manager，专用于reaper相关测试：
+// 两个工厂始终返回同一个noop实现，CreateSession的非空校验即可通过。
+// 测试里的到期场景都是通过forceExpiry手工构造后同步调用reapExpired来断言的，
+// 这里立即取消后台runReaper协程，避免它按真实wall clock跑起来和显式调用的
+// reapExpired()产生竞争，使测试结果不依赖调度时序
+func newTestManager() *Manager {
+	m := NewManager(
+		WithRecognizerFactory(func(SessionMeta) asr.Recognizer { return noopRecognizer{} }),
+		WithVADStrategy(func(SessionMeta) pool.VADPoolInterface { return noopVADPool{} }),
+	)
+	m.cancel()
+	return m
+}
+
+// forceExpiry直接操纵session.LastSeen并推入一条匹配的过期堆条目，绕开真实的
+// time.Sleep等待，使到期/未到期场景可以在测试里确定性地构造
+func forceExpiry(m *Manager, session *Session, lastSeen int64) {
+	session.mu.Lock()
+	session.LastSeen = lastSeen
+	session.mu.Unlock()
+	m.pushExpiry(session, lastSeen)
+}
+
+// TestReapExpired_ClosesOnlyExpiredSessions创建若干活跃度参差不齐的会话，
+// 验证reapExpired恰好回收已到期的那些，活跃会话原样保留
+func TestReapExpired_ClosesOnlyExpiredSessions(t *testing.T) {
+	m := newTestManager()
+	defer m.Shutdown()
+
+	now := time.Now().UnixNano()
+
+	expiredIDs := []string{"expired-1", "expired-2", "expired-3"}
+	for _, id := range expiredIDs {
+		sess, err := m.CreateSession(id, nil)
+		if err != nil {
+			t.Fatalf("CreateSession(%s) failed: %v", id, err)
+		}
+		// LastSeen设在一小时前，idleTimeout默认5分钟，因此早已过期
+		forceExpiry(m, sess, now-time.Hour.Nanoseconds())
+	}
+
+	activeIDs := []string{"active-1", "active-2"}
+	for _, id := range activeIDs {
+		sess, err := m.CreateSession(id, nil)
+		if err != nil {
+			t.Fatalf("CreateSession(%s) failed: %v", id, err)
+		}
+		// LastSeen就是现在，距到期还有接近完整的idleTimeout
+		forceExpiry(m, sess, now)
+	}
+
+	m.reapExpired()
+
+	for _, id := range expiredIDs {
+		if _, exists := m.GetSession(id); exists {
+			t.Errorf("session %s should have been reaped but still exists", id)
+		}
+	}
+	for _, id := range activeIDs {
+		if _, exists := m.GetSession(id); !exists {
+			t.Errorf("session %s should still be active but was reaped", id)
+		}
+	}
+
+	if got, want := atomic.LoadInt64(&m.expiredSessions), int64(len(expiredIDs)); got != want {
+		t.Errorf("expiredSessions = %d, want %d", got, want)
+	}
+}
+
+// TestReapExpired_SkipsStaleEntryAfterRefresh验证"懒丢弃"语义：一条已经到期的堆条目，
+// 如果会话在此期间被刷新过(LastSeen不再匹配该条目的快照)，reapExpired必须跳过它而不是
+// 误杀一个实际仍然活跃的会话
+func TestReapExpired_SkipsStaleEntryAfterRefresh(t *testing.T) {
+	m := newTestManager()
+	defer m.Shutdown()
+
+	now := time.Now().UnixNano()
+	sess, err := m.CreateSession("refreshed", nil)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	// 第一条条目：LastSeen是一小时前，已经过期
+	staleLastSeen := now - time.Hour.Nanoseconds()
+	forceExpiry(m, sess, staleLastSeen)
+
+	// 会话随后被刷新：LastSeen更新为当前时间，并推入一条新的、尚未到期的条目。
+	// 堆里此时同时存在旧的(已过期)和新的(未过期)两条条目。
+	forceExpiry(m, sess, now)
+
+	m.reapExpired()
+
+	if _, exists := m.GetSession("refreshed"); !exists {
+		t.Errorf("session refreshed after a stale expiry entry should not be reaped")
+	}
+	if got := atomic.LoadInt64(&m.expiredSessions); got != 0 {
+		t.Errorf("expiredSessions = %d, want 0", got)
+	}
+}
+
+// TestPushExpiry_HardCapOverridesIdleTimeout验证MaxSessionAge硬上限在早于
+// 空闲超时时生效：过期时间取两者中较早的一个
+func TestPushExpiry_HardCapOverridesIdleTimeout(t *testing.T) {
+	m := newTestManager()
+	defer m.Shutdown()
+
+	m.idleTimeout = time.Hour
+	m.maxSessionAge = time.Minute
+
+	// CreateSession内部会以当前的idleTimeout/maxSessionAge为该会话pushExpiry一次，
+	// 这里不再手工重复推入，避免CreatedAt被二次读取产生的纳秒级时间差让断言变得脆弱
+	sess, err := m.CreateSession("hard-capped", nil)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	wantExpiresAt := sess.CreatedAt + m.maxSessionAge.Nanoseconds()
+
+	m.expiryMu.Lock()
+	if len(m.expiryHeap) == 0 {
+		m.expiryMu.Unlock()
+		t.Fatalf("expiryHeap is empty after CreateSession")
+	}
+	got := m.expiryHeap[0].expiresAt
+	m.expiryMu.Unlock()
+
+	if got != wantExpiresAt {
+		t.Errorf("expiresAt = %d, want %d (hard cap from CreatedAt+MaxSessionAge)", got, wantExpiresAt)
+	}
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GenerateResumeToken 生成会话恢复令牌：HMAC-SHA256(secret, sessionID || issuedAt)。
+// issuedAt (UnixNano) 不下发给客户端，而是保存在服务端的Session.resumeIssuedAt上；
+// 验证时取该值按相同输入重算HMAC即可
+func GenerateResumeToken(secret []byte, sessionID string, issuedAt int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sessionID))
+	mac.Write([]byte(strconv.FormatInt(issuedAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyResumeToken 常数时间校验令牌，并按ttl核验是否已过期；ttl<=0表示不设过期时间
+func VerifyResumeToken(secret []byte, sessionID, token string, issuedAt int64, ttl time.Duration, now time.Time) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("session resumption is disabled")
+	}
+	if ttl > 0 && now.Sub(time.Unix(0, issuedAt)) > ttl {
+		return fmt.Errorf("resume token expired")
+	}
+	expected := GenerateResumeToken(secret, sessionID, issuedAt)
+	if !hmac.Equal([]byte(expected), []byte(token)) {
+		return fmt.Errorf("resume token mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !darwin
+
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"asr_server/internal/pool"
+)
+
+// processTenVAD 处理TEN-VAD。与processWebRTCVAD/processEnergyVAD共享processGenericVAD，
+// 仅darwin下不可用（见vad_ten_darwin.go）
+func (m *Manager) processTenVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32) error {
+	instance, ok := session.VADInstance.(*pool.TenVADInstance)
+	if !ok {
+		return fmt.Errorf("invalid TEN-VAD instance type")
+	}
+	return m.processGenericVAD(ctx, session, sessionID, float32Slice, instance)
+}
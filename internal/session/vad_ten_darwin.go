@@ -0,0 +1,14 @@
+//go:build darwin
+
+package session
+
+import (
+	"context"
+	"fmt"
+)
+
+// processTenVAD TEN-VAD在macOS上保持禁用（见internal/pool/vad_factory_ten_darwin.go），
+// 正常情况下不会被调用到，因为VADFactory在darwin下不会注册TEN_VAD_TYPE
+func (m *Manager) processTenVAD(ctx context.Context, session *Session, sessionID string, float32Slice []float32) error {
+	return fmt.Errorf("TEN-VAD is not supported on darwin")
+}
@@ -0,0 +1,326 @@
+package speaker
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// annNode 是HNSW图中的一个节点，对应某个说话人的某一条embedding样本
+type annNode struct {
+	speakerID string
+	sampleIdx int
+	vector    []float32 // 已做L2归一化
+	level     int
+	neighbors [][]int // 按层存储的邻居节点id
+	deleted   bool    // 墓碑标记，DeleteSpeaker时置位，遍历时跳过
+}
+
+// ANNIndex 是一个面向余弦相似度场景的HNSW近似最近邻索引
+type ANNIndex struct {
+	mu sync.RWMutex
+
+	nodes       []*annNode
+	entryPoint  int
+	maxLevel    int
+	m           int // 每层最大邻居数
+	efConstruct int // 建图阶段的候选集大小
+	efSearch    int // 查询阶段的候选集大小
+	levelMult   float64
+}
+
+// NewANNIndex 创建一个新的HNSW索引
+func NewANNIndex(m, efConstruction, efSearch int) *ANNIndex {
+	if m <= 0 {
+		m = 16
+	}
+	if efConstruction <= 0 {
+		efConstruction = 200
+	}
+	if efSearch <= 0 {
+		efSearch = 64
+	}
+
+	return &ANNIndex{
+		entryPoint:  -1,
+		m:           m,
+		efConstruct: efConstruction,
+		efSearch:    efSearch,
+		levelMult:   1.0 / math.Log(float64(m)),
+	}
+}
+
+// normalize 对向量做L2归一化，使点积等价于余弦相似度
+func normalize(v []float32) []float32 {
+	var normSq float32
+	for _, x := range v {
+		normSq += x * x
+	}
+	if normSq == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(float64(normSq)))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+// dot 计算两个已归一化向量的点积，即余弦相似度
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// randomLevel 按几何分布为新节点挑选层数
+func (idx *ANNIndex) randomLevel() int {
+	level := 0
+	for rand.Float64() < 1.0/float64(idx.m) && level < 32 {
+		level++
+	}
+	return level
+}
+
+type candidate struct {
+	id   int
+	dist float32 // 距离：1 - 余弦相似度，越小越近
+}
+
+// minHeap / maxHeap 复用同一结构，通过less字段区分排序方向
+type candidateHeap struct {
+	items []candidate
+	less  func(a, b candidate) bool
+}
+
+func (h candidateHeap) Len() int            { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool  { return h.less(h.items[i], h.items[j]) }
+func (h candidateHeap) Swap(i, j int)       { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	h.items = old[:n-1]
+	return item
+}
+
+func newMinHeap() *candidateHeap {
+	return &candidateHeap{less: func(a, b candidate) bool { return a.dist < b.dist }}
+}
+
+func newMaxHeap() *candidateHeap {
+	return &candidateHeap{less: func(a, b candidate) bool { return a.dist > b.dist }}
+}
+
+// searchLayer 在给定层上从entry点出发做贪心/beam搜索，返回ef个最近邻候选
+func (idx *ANNIndex) searchLayer(query []float32, entry int, ef int, level int) []candidate {
+	visited := map[int]bool{entry: true}
+
+	entryDist := 1 - dot(query, idx.nodes[entry].vector)
+	candidates := newMinHeap()
+	heap.Push(candidates, candidate{id: entry, dist: entryDist})
+
+	results := newMaxHeap()
+	if !idx.nodes[entry].deleted {
+		heap.Push(results, candidate{id: entry, dist: entryDist})
+	}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(candidate)
+
+		if results.Len() >= ef {
+			worst := results.items[0]
+			if current.dist > worst.dist {
+				break
+			}
+		}
+
+		if level >= len(idx.nodes[current.id].neighbors) {
+			continue
+		}
+
+		for _, neighborID := range idx.nodes[current.id].neighbors[level] {
+			if visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+
+			neighbor := idx.nodes[neighborID]
+			d := 1 - dot(query, neighbor.vector)
+
+			if results.Len() < ef {
+				heap.Push(candidates, candidate{id: neighborID, dist: d})
+				if !neighbor.deleted {
+					heap.Push(results, candidate{id: neighborID, dist: d})
+				}
+			} else if d < results.items[0].dist {
+				heap.Push(candidates, candidate{id: neighborID, dist: d})
+				if !neighbor.deleted {
+					heap.Push(results, candidate{id: neighborID, dist: d})
+					if results.Len() > ef {
+						heap.Pop(results)
+					}
+				}
+			}
+		}
+	}
+
+	return results.items
+}
+
+// selectNeighbors 从候选集中挑出最多m个邻居：启发式规则是，
+// 只有当候选点到查询点的距离不大于它到每个已选中邻居的距离时才保留，避免图退化成一堆挤在一起的点
+func (idx *ANNIndex) selectNeighbors(query []float32, candidates []candidate, m int) []int {
+	minHeap := newMinHeap()
+	minHeap.items = append(minHeap.items, candidates...)
+	heap.Init(minHeap)
+
+	selected := make([]int, 0, m)
+	selectedVecs := make([][]float32, 0, m)
+
+	for minHeap.Len() > 0 && len(selected) < m {
+		cand := heap.Pop(minHeap).(candidate)
+		vec := idx.nodes[cand.id].vector
+
+		good := true
+		for _, sv := range selectedVecs {
+			if 1-dot(vec, sv) < cand.dist {
+				good = false
+				break
+			}
+		}
+
+		if good {
+			selected = append(selected, cand.id)
+			selectedVecs = append(selectedVecs, vec)
+		}
+	}
+
+	return selected
+}
+
+// Insert 向索引中插入一条新的embedding样本
+func (idx *ANNIndex) Insert(speakerID string, sampleIdx int, vector []float32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	node := &annNode{
+		speakerID: speakerID,
+		sampleIdx: sampleIdx,
+		vector:    normalize(vector),
+		level:     idx.randomLevel(),
+	}
+	node.neighbors = make([][]int, node.level+1)
+
+	id := len(idx.nodes)
+	idx.nodes = append(idx.nodes, node)
+
+	if idx.entryPoint == -1 {
+		idx.entryPoint = id
+		idx.maxLevel = node.level
+		return
+	}
+
+	entry := idx.entryPoint
+	// 从最高层贪心下降到新节点的最高层+1
+	for level := idx.maxLevel; level > node.level; level-- {
+		nearest := idx.searchLayer(node.vector, entry, 1, level)
+		if len(nearest) > 0 {
+			entry = nearest[0].id
+		}
+	}
+
+	// 从新节点的层开始，每层做beam search并连接邻居
+	for level := min(node.level, idx.maxLevel); level >= 0; level-- {
+		candidates := idx.searchLayer(node.vector, entry, idx.efConstruct, level)
+		neighbors := idx.selectNeighbors(node.vector, candidates, idx.m)
+		node.neighbors[level] = neighbors
+
+		// 双向连接，并在对端也做一次裁剪
+		for _, nb := range neighbors {
+			nbNode := idx.nodes[nb]
+			if level >= len(nbNode.neighbors) {
+				continue
+			}
+			nbNode.neighbors[level] = append(nbNode.neighbors[level], id)
+			if len(nbNode.neighbors[level]) > idx.m {
+				cands := make([]candidate, 0, len(nbNode.neighbors[level]))
+				for _, other := range nbNode.neighbors[level] {
+					cands = append(cands, candidate{id: other, dist: 1 - dot(nbNode.vector, idx.nodes[other].vector)})
+				}
+				nbNode.neighbors[level] = idx.selectNeighbors(nbNode.vector, cands, idx.m)
+			}
+		}
+
+		if len(candidates) > 0 {
+			entry = candidates[0].id
+		}
+	}
+
+	if node.level > idx.maxLevel {
+		idx.maxLevel = node.level
+		idx.entryPoint = id
+	}
+}
+
+// Search 返回与query最相似的说话人ID及其相似度（1-距离）
+// 墓碑节点在搜索过程中被跳过
+func (idx *ANNIndex) Search(query []float32, ef int) (string, float32, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if idx.entryPoint == -1 {
+		return "", 0, false
+	}
+
+	if ef <= 0 {
+		ef = idx.efSearch
+	}
+
+	queryNorm := normalize(query)
+	entry := idx.entryPoint
+	for level := idx.maxLevel; level > 0; level-- {
+		nearest := idx.searchLayer(queryNorm, entry, 1, level)
+		if len(nearest) > 0 {
+			entry = nearest[0].id
+		}
+	}
+
+	results := idx.searchLayer(queryNorm, entry, ef, 0)
+	if len(results) == 0 {
+		return "", 0, false
+	}
+
+	best := results[0]
+	for _, c := range results {
+		if c.dist < best.dist {
+			best = c
+		}
+	}
+
+	return idx.nodes[best.id].speakerID, 1 - best.dist, true
+}
+
+// Tombstone 将某个说话人的全部节点标记为已删除，遍历时跳过但不回收图结构
+func (idx *ANNIndex) Tombstone(speakerID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, node := range idx.nodes {
+		if node.speakerID == speakerID {
+			node.deleted = true
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -2,24 +2,57 @@ package speaker
 
 import (
 	"asr_server/config"
+	"asr_server/internal/codec"
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-audio/wav"
 )
 
+// extToCodec 按上传文件扩展名推断编解码器名称，供parseAudioFile对非WAV格式分发解码。
+// WAV单独走parseWAVFile的分支，不经过这张表
+var extToCodec = map[string]string{
+	".flac": codec.FLAC,
+	".raw":  codec.PCM16LE,
+	".pcm":  codec.PCM16LE,
+	".ulaw": codec.PCMMuLaw,
+	".ua":   codec.PCMMuLaw,
+	".alaw": codec.PCMALaw,
+	".al":   codec.PCMALaw,
+}
+
+// chunkUpload 累积某次分片上传尚未组装完成的音频数据；register场景下speaker_id/
+// speaker_name可以在任意一个分片里带上（包括最终分片本身），只要求最终分片处理时已经确定
+type chunkUpload struct {
+	format      string
+	sampleRate  int
+	data        []byte
+	speakerID   string
+	speakerName string
+}
+
 // Handler 声纹识别HTTP处理器
 type Handler struct {
 	manager *Manager
+
+	uploadsMu sync.Mutex
+	uploads   map[string]*chunkUpload
 }
 
 // NewHandler 创建新的处理器
 func NewHandler(manager *Manager) *Handler {
 	return &Handler{
 		manager: manager,
+		uploads: make(map[string]*chunkUpload),
 	}
 }
 
@@ -48,6 +81,10 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 		//Base64 注册与识别接口
 		speakerGroup.POST("/register_base64", h.RegisterSpeakerBase64)
 		speakerGroup.POST("/identify_base64", h.IdentifySpeakerBase64)
+
+		// 分片流式注册与识别接口，供大文件/弱网客户端分批上传音频
+		speakerGroup.POST("/register/chunk", h.RegisterSpeakerChunk)
+		speakerGroup.POST("/identify/chunk", h.IdentifySpeakerChunk)
 	}
 }
 
@@ -82,7 +119,7 @@ func (h *Handler) RegisterSpeaker(c *gin.Context) {
 	defer file.Close()
 
 	// 解析音频数据
-	audioData, sampleRate, err := h.parseAudioFile(file, header)
+	audioData, sampleRate, err := h.parseAudioFile(c, file, header)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": fmt.Sprintf("failed to parse audio file: %v", err),
@@ -90,19 +127,25 @@ func (h *Handler) RegisterSpeaker(c *gin.Context) {
 		return
 	}
 
-	// 注册声纹
-	err = h.manager.RegisterSpeaker(speakerID, speakerName, audioData, sampleRate)
+	// 注册声纹（含录入前置质量检查）
+	report, err := h.manager.RegisterSpeaker(speakerID, speakerName, audioData, sampleRate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to register speaker: %v", err),
+		status := http.StatusInternalServerError
+		if report != nil && !report.Passed {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":          fmt.Sprintf("failed to register speaker: %v", err),
+			"quality_report": report,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":      "Speaker registered successfully",
-		"speaker_id":   speakerID,
-		"speaker_name": speakerName,
+		"message":        "Speaker registered successfully",
+		"speaker_id":     speakerID,
+		"speaker_name":   speakerName,
+		"quality_report": report,
 	})
 }
 
@@ -119,7 +162,7 @@ func (h *Handler) IdentifySpeaker(c *gin.Context) {
 	defer file.Close()
 
 	// 解析音频数据
-	audioData, sampleRate, err := h.parseAudioFile(file, header)
+	audioData, sampleRate, err := h.parseAudioFile(c, file, header)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": fmt.Sprintf("failed to parse audio file: %v", err),
@@ -160,7 +203,7 @@ func (h *Handler) VerifySpeaker(c *gin.Context) {
 	defer file.Close()
 
 	// 解析音频数据
-	audioData, sampleRate, err := h.parseAudioFile(file, header)
+	audioData, sampleRate, err := h.parseAudioFile(c, file, header)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": fmt.Sprintf("failed to parse audio file: %v", err),
@@ -225,16 +268,67 @@ func (h *Handler) GetStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// parseAudioFile 解析音频文件
-func (h *Handler) parseAudioFile(file multipart.File, header *multipart.FileHeader) ([]float32, int, error) {
-	// 检查文件类型
+// parseAudioFile 解析音频文件。WAV按文件头自带的采样率/声道数解析；
+// 其余格式（flac/raw/pcm/ulaw/alaw）按扩展名分发给codec包对应的Decoder，
+// 与/ws流式接入复用同一套编解码实现，避免两套解码逻辑分叉
+func (h *Handler) parseAudioFile(c *gin.Context, file multipart.File, header *multipart.FileHeader) ([]float32, int, error) {
 	filename := strings.ToLower(header.Filename)
-	if !strings.HasSuffix(filename, ".wav") {
-		return nil, 0, fmt.Errorf("only WAV files are supported")
+	ext := filepath.Ext(filename)
+
+	if ext == ".wav" {
+		return h.parseWAVFile(file)
+	}
+
+	codecName, supported := extToCodec[ext]
+	if !supported {
+		return nil, 0, fmt.Errorf("unsupported audio file extension: %s", ext)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read audio file: %v", err)
+	}
+
+	// PCM/µ-law/A-law是裸流，文件本身不携带采样率，需由调用方通过表单字段声明；
+	// FLAC在STREAMINFO中自带采样率，sample_rate表单字段可省略
+	sampleRate, _ := strconv.Atoi(c.PostForm("sample_rate"))
+	return h.decodeAudioBytes(data, codecName, sampleRate)
+}
+
+// decodeAudioBytes 按format解码一段完整的音频字节。format为空或"wav"走WAV解析，
+// 其余值按codec包支持的编解码器名称（flac/pcm_s16le/pcm_mulaw/pcm_alaw/opus）解码；
+// Base64接口与分片上传接口都复用这一份逻辑，与parseAudioFile的文件上传路径殊途同归
+func (h *Handler) decodeAudioBytes(data []byte, format string, sampleRate int) ([]float32, int, error) {
+	format = strings.ToLower(format)
+	if format == "" || format == "wav" {
+		return h.parseWAVFile(bytes.NewReader(data))
 	}
 
+	decoder, err := codec.NewDecoder(format, sampleRate, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create %s decoder: %v", format, err)
+	}
+
+	samples, err := decoder.Decode(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s audio: %v", format, err)
+	}
+
+	if sampleRate <= 0 {
+		sampleRate = decoder.SampleRate()
+	}
+	if sampleRate <= 0 {
+		return nil, 0, fmt.Errorf("sample_rate is required for %s audio", format)
+	}
+
+	return samples, sampleRate, nil
+}
+
+// parseWAVFile 解析WAV数据，沿用既有的单声道/立体声转换逻辑；r可以是上传的
+// multipart.File，也可以是Base64/分片上传组装出的bytes.Reader，两者都实现io.ReadSeeker
+func (h *Handler) parseWAVFile(r io.ReadSeeker) ([]float32, int, error) {
 	// 读取WAV文件
-	decoder := wav.NewDecoder(file)
+	decoder := wav.NewDecoder(r)
 	if !decoder.IsValidFile() {
 		return nil, 0, fmt.Errorf("invalid WAV file")
 	}
@@ -258,7 +352,7 @@ func (h *Handler) parseAudioFile(file multipart.File, header *multipart.FileHead
 	samples := make([]float32, len(buffer.Data))
 	for i, sample := range buffer.Data {
 		// 将int转换为float32，范围[-1.0, 1.0]
-		samples[i] = float32(sample) / config.GlobalConfig.Audio.NormalizeFactor
+		samples[i] = float32(sample) / config.Load().Audio.NormalizeFactor
 	}
 
 	// 如果是立体声，转换为单声道（取平均值）
@@ -273,15 +367,15 @@ func (h *Handler) parseAudioFile(file multipart.File, header *multipart.FileHead
 	return samples, sampleRate, nil
 }
 
-// 添加基于Base64的API接口（可选）
-
-// RegisterSpeakerBase64 使用Base64编码的音频数据注册声纹
+// RegisterSpeakerBase64 使用Base64编码的音频数据注册声纹。format留空时按WAV解析，
+// 否则按codec包支持的编解码器名称解码，与文件上传接口共用decodeAudioBytes
 func (h *Handler) RegisterSpeakerBase64(c *gin.Context) {
 	var req struct {
 		SpeakerID   string `json:"speaker_id" binding:"required"`
 		SpeakerName string `json:"speaker_name" binding:"required"`
-		AudioData   string `json:"audio_data" binding:"required"` // Base64编码的WAV数据
-		SampleRate  int    `json:"sample_rate" binding:"required"`
+		AudioData   string `json:"audio_data" binding:"required"` // Base64编码的音频数据
+		Format      string `json:"format"`                        // 空值/wav/flac/pcm_s16le/pcm_mulaw/pcm_alaw
+		SampleRate  int    `json:"sample_rate"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -291,19 +385,49 @@ func (h *Handler) RegisterSpeakerBase64(c *gin.Context) {
 		return
 	}
 
-	// 这里可以添加Base64解码和音频处理逻辑
-	// 为简化示例，暂时跳过具体实现
+	data, err := base64.StdEncoding.DecodeString(req.AudioData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid base64 audio_data: %v", err),
+		})
+		return
+	}
 
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "Base64 API not implemented yet",
+	audioData, sampleRate, err := h.decodeAudioBytes(data, req.Format, req.SampleRate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("failed to decode audio: %v", err),
+		})
+		return
+	}
+
+	report, err := h.manager.RegisterSpeaker(req.SpeakerID, req.SpeakerName, audioData, sampleRate)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if report != nil && !report.Passed {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":          fmt.Sprintf("failed to register speaker: %v", err),
+			"quality_report": report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Speaker registered successfully",
+		"speaker_id":     req.SpeakerID,
+		"speaker_name":   req.SpeakerName,
+		"quality_report": report,
 	})
 }
 
 // IdentifySpeakerBase64 使用Base64编码的音频数据识别声纹
 func (h *Handler) IdentifySpeakerBase64(c *gin.Context) {
 	var req struct {
-		AudioData  string `json:"audio_data" binding:"required"` // Base64编码的WAV数据
-		SampleRate int    `json:"sample_rate" binding:"required"`
+		AudioData  string `json:"audio_data" binding:"required"` // Base64编码的音频数据
+		Format     string `json:"format"`                        // 空值/wav/flac/pcm_s16le/pcm_mulaw/pcm_alaw
+		SampleRate int    `json:"sample_rate"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -313,10 +437,177 @@ func (h *Handler) IdentifySpeakerBase64(c *gin.Context) {
 		return
 	}
 
-	// 这里可以添加Base64解码和音频处理逻辑
-	// 为简化示例，暂时跳过具体实现
+	data, err := base64.StdEncoding.DecodeString(req.AudioData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid base64 audio_data: %v", err),
+		})
+		return
+	}
+
+	audioData, sampleRate, err := h.decodeAudioBytes(data, req.Format, req.SampleRate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("failed to decode audio: %v", err),
+		})
+		return
+	}
+
+	result, err := h.manager.IdentifySpeaker(audioData, sampleRate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("failed to identify speaker: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// chunkUploadRequest 分片流式上传的请求体，register/identify两个端点共用同一套字段，
+// speaker_id/speaker_name仅register场景需要
+type chunkUploadRequest struct {
+	UploadID    string `json:"upload_id" binding:"required"`
+	SpeakerID   string `json:"speaker_id"`
+	SpeakerName string `json:"speaker_name"`
+	Format      string `json:"format"` // 空值/wav/flac/pcm_s16le/pcm_mulaw/pcm_alaw，只需在任意一个分片中声明一次
+	SampleRate  int    `json:"sample_rate"`
+	ChunkIndex  int    `json:"chunk_index"`
+	IsFinal     bool   `json:"is_final"`                       // gin的binding:"required"对bool零值false不友好，故这里不加该tag
+	AudioChunk  string `json:"audio_chunk" binding:"required"` // 该分片的Base64编码原始字节
+}
+
+// appendChunk 将一个分片追加到upload_id对应的缓冲区；首次出现的upload_id会新建缓冲区，
+// format/sample_rate只要任意一次请求带上就会被记住，供最终分片解码时使用
+func (h *Handler) appendChunk(req chunkUploadRequest, chunk []byte) *chunkUpload {
+	h.uploadsMu.Lock()
+	defer h.uploadsMu.Unlock()
+
+	upload, exists := h.uploads[req.UploadID]
+	if !exists {
+		upload = &chunkUpload{}
+		h.uploads[req.UploadID] = upload
+	}
+	if req.Format != "" {
+		upload.format = req.Format
+	}
+	if req.SampleRate > 0 {
+		upload.sampleRate = req.SampleRate
+	}
+	if req.SpeakerID != "" {
+		upload.speakerID = req.SpeakerID
+	}
+	if req.SpeakerName != "" {
+		upload.speakerName = req.SpeakerName
+	}
+	upload.data = append(upload.data, chunk...)
+	return upload
+}
 
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "Base64 API not implemented yet",
+// RegisterSpeakerChunk 接收分片上传的音频数据并在最后一片到达后完成注册；
+// 中间分片只做缓冲，不调用声纹引擎，避免逐片重复解码
+func (h *Handler) RegisterSpeakerChunk(c *gin.Context) {
+	var req chunkUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := base64.StdEncoding.DecodeString(req.AudioChunk)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid base64 audio_chunk: %v", err)})
+		return
+	}
+
+	upload := h.appendChunk(req, chunk)
+
+	if !req.IsFinal {
+		c.JSON(http.StatusAccepted, gin.H{
+			"upload_id":   req.UploadID,
+			"chunk_index": req.ChunkIndex,
+			"received":    len(upload.data),
+		})
+		return
+	}
+
+	h.uploadsMu.Lock()
+	delete(h.uploads, req.UploadID)
+	h.uploadsMu.Unlock()
+
+	if upload.speakerID == "" || upload.speakerName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "speaker_id and speaker_name must be set in the final chunk or an earlier one"})
+		return
+	}
+
+	audioData, sampleRate, err := h.decodeAudioBytes(upload.data, upload.format, upload.sampleRate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to decode audio: %v", err)})
+		return
+	}
+
+	report, err := h.manager.RegisterSpeaker(upload.speakerID, upload.speakerName, audioData, sampleRate)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if report != nil && !report.Passed {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":          fmt.Sprintf("failed to register speaker: %v", err),
+			"quality_report": report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Speaker registered successfully",
+		"speaker_id":     upload.speakerID,
+		"speaker_name":   upload.speakerName,
+		"quality_report": report,
 	})
 }
+
+// IdentifySpeakerChunk 接收分片上传的音频数据并在最后一片到达后完成识别
+func (h *Handler) IdentifySpeakerChunk(c *gin.Context) {
+	var req chunkUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := base64.StdEncoding.DecodeString(req.AudioChunk)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid base64 audio_chunk: %v", err)})
+		return
+	}
+
+	upload := h.appendChunk(req, chunk)
+
+	if !req.IsFinal {
+		c.JSON(http.StatusAccepted, gin.H{
+			"upload_id":   req.UploadID,
+			"chunk_index": req.ChunkIndex,
+			"received":    len(upload.data),
+		})
+		return
+	}
+
+	h.uploadsMu.Lock()
+	delete(h.uploads, req.UploadID)
+	h.uploadsMu.Unlock()
+
+	audioData, sampleRate, err := h.decodeAudioBytes(upload.data, upload.format, upload.sampleRate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to decode audio: %v", err)})
+		return
+	}
+
+	result, err := h.manager.IdentifySpeaker(audioData, sampleRate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("failed to identify speaker: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
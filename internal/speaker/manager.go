@@ -1,12 +1,8 @@
 package speaker
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -36,12 +32,17 @@ type SpeakerDatabase struct {
 type Manager struct {
 	extractor    *sherpa.SpeakerEmbeddingExtractor
 	manager      *sherpa.SpeakerEmbeddingManager
+	store        SpeakerStore
 	database     *SpeakerDatabase
-	dbPath       string
 	threshold    float32
 	embeddingDim int
 	mutex        sync.RWMutex
 	dataDir      string
+
+	annEnabled bool
+	annIndex   *ANNIndex
+
+	quality QualityThresholds
 }
 
 // Config 声纹识别配置
@@ -51,13 +52,35 @@ type Config struct {
 	Provider   string  `json:"provider"`
 	Threshold  float32 `json:"threshold"`
 	DataDir    string  `json:"data_dir"`
+
+	// StorageDriver 声纹数据库的存储后端："json"（默认）、"mysql"、"redis"
+	StorageDriver string `json:"storage_driver"`
+	// DSN 数据库连接串，仅在 StorageDriver 为 mysql/redis 时需要
+	DSN string `json:"dsn"`
+
+	// ANNEnabled 是否使用HNSW近似最近邻索引加速IdentifySpeaker，关闭时退回精确的线性扫描
+	ANNEnabled bool `json:"ann_enabled"`
+	// ANNM 每个节点每层保留的最大邻居数
+	ANNM int `json:"ann_m"`
+	// ANNEfSearch 查询阶段的候选集大小，越大越精确但越慢
+	ANNEfSearch int `json:"ann_ef_search"`
+
+	// MinVoicedSeconds 录入音频要求的最少有效语音时长，默认1.5秒
+	MinVoicedSeconds float32 `json:"min_voiced_seconds"`
+	// MinSNRDb 录入音频要求的最低信噪比（dB）
+	MinSNRDb float32 `json:"min_snr_db"`
+	// MaxClippingRatio 录入音频允许的最大削波采样点占比
+	MaxClippingRatio float32 `json:"max_clipping_ratio"`
+	// MaxDuplicateSimilarity 与已有样本的余弦相似度超过该值则判定为重复样本并拒绝
+	MaxDuplicateSimilarity float32 `json:"max_duplicate_similarity"`
 }
 
 // NewManager 创建声纹识别管理器
 func NewManager(config *Config) (*Manager, error) {
-	// 确保数据目录存在
-	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	// 创建存储后端
+	store, err := NewStore(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create speaker store: %v", err)
 	}
 
 	// 创建声纹特征提取器配置
@@ -88,13 +111,19 @@ func NewManager(config *Config) (*Manager, error) {
 	manager := &Manager{
 		extractor:    extractor,
 		manager:      embeddingManager,
+		store:        store,
 		threshold:    config.Threshold,
 		embeddingDim: dim,
 		dataDir:      config.DataDir,
-		dbPath:       filepath.Join(config.DataDir, "speaker.json"),
+		annEnabled:   config.ANNEnabled,
+		quality:      qualityThresholdsFromConfig(config),
+	}
+
+	if config.ANNEnabled {
+		manager.annIndex = NewANNIndex(config.ANNM, 200, config.ANNEfSearch)
 	}
 
-	// 加载现有数据库
+	// 从存储后端加载现有数据库
 	if err := manager.loadDatabase(); err != nil {
 		logger.Infof("Warning: failed to load existing database: %v", err)
 		manager.database = &SpeakerDatabase{
@@ -120,41 +149,25 @@ func (m *Manager) Close() {
 	if m.manager != nil {
 		sherpa.DeleteSpeakerEmbeddingManager(m.manager)
 	}
-}
-
-// loadDatabase 从文件加载声纹数据库
-func (m *Manager) loadDatabase() error {
-	if _, err := os.Stat(m.dbPath); os.IsNotExist(err) {
-		return fmt.Errorf("database file does not exist")
-	}
-
-	data, err := ioutil.ReadFile(m.dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to read database file: %v", err)
-	}
-
-	var db SpeakerDatabase
-	if err := json.Unmarshal(data, &db); err != nil {
-		return fmt.Errorf("failed to unmarshal database: %v", err)
+	if m.store != nil {
+		if err := m.store.Close(); err != nil {
+			logger.Warnf("Warning: failed to close speaker store: %v", err)
+		}
 	}
-
-	m.database = &db
-	return nil
 }
 
-// saveDatabase 保存声纹数据库到文件
-func (m *Manager) saveDatabase() error {
-	m.database.UpdatedAt = time.Now()
-
-	data, err := json.MarshalIndent(m.database, "", "  ")
+// loadDatabase 从存储后端加载声纹数据库到内存
+func (m *Manager) loadDatabase() error {
+	speakers, err := m.store.LoadAll()
 	if err != nil {
-		return fmt.Errorf("failed to marshal database: %v", err)
+		return fmt.Errorf("failed to load speakers from store: %v", err)
 	}
 
-	if err := ioutil.WriteFile(m.dbPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write database file: %v", err)
+	m.database = &SpeakerDatabase{
+		Speakers:  speakers,
+		Version:   "1.0.0",
+		UpdatedAt: time.Now(),
 	}
-
 	return nil
 }
 
@@ -173,6 +186,12 @@ func (m *Manager) loadSpeakersToMemory() error {
 				loadedCount++
 				totalEmbeddings += len(speakerData.Embeddings)
 			}
+
+			if m.annIndex != nil {
+				for i, embedding := range speakerData.Embeddings {
+					m.annIndex.Insert(speakerID, i, embedding)
+				}
+			}
 		}
 	}
 
@@ -181,6 +200,11 @@ func (m *Manager) loadSpeakersToMemory() error {
 	return nil
 }
 
+// ExtractEmbedding 从音频数据提取声纹特征（导出版本，供会话层的实时分离复用）
+func (m *Manager) ExtractEmbedding(audioData []float32, sampleRate int) ([]float32, error) {
+	return m.extractEmbedding(audioData, sampleRate)
+}
+
 // extractEmbedding 从音频数据提取声纹特征
 func (m *Manager) extractEmbedding(audioData []float32, sampleRate int) ([]float32, error) {
 	// 创建音频流
@@ -224,6 +248,11 @@ func (m *Manager) calculateSimilarity(queryEmbedding []float32, storedEmbeddings
 	return maxSimilarity
 }
 
+// CosineSimilarity 计算两个向量的余弦相似度（导出版本，供会话层的匿名说话人质心匹配复用）
+func CosineSimilarity(a, b []float32) float32 {
+	return cosineSimilarity(a, b)
+}
+
 // cosineSimilarity 计算两个向量的余弦相似度
 func cosineSimilarity(a, b []float32) float32 {
 	if len(a) != len(b) {
@@ -247,51 +276,88 @@ func cosineSimilarity(a, b []float32) float32 {
 }
 
 // RegisterSpeaker 注册声纹
-func (m *Manager) RegisterSpeaker(speakerID, speakerName string, audioData []float32, sampleRate int) error {
+func (m *Manager) RegisterSpeaker(speakerID, speakerName string, audioData []float32, sampleRate int) (*EnrollmentQualityReport, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	// 提取声纹特征
 	embedding, err := m.extractEmbedding(audioData, sampleRate)
 	if err != nil {
-		return fmt.Errorf("failed to extract embedding: %v", err)
+		return nil, fmt.Errorf("failed to extract embedding: %v", err)
 	}
 
 	// 检查说话人是否已存在
 	speakerData, exists := m.database.Speakers[speakerID]
+
+	// 录入前置质量检查：时长、信噪比、削波，以及与已有样本的重复度
+	var maxSimilarity float32
+	if exists {
+		maxSimilarity = m.calculateSimilarity(embedding, speakerData.Embeddings)
+	}
+	report := assessEnrollmentQuality(audioData, sampleRate, maxSimilarity, exists, m.quality)
+	if !report.Passed {
+		return report, fmt.Errorf("enrollment audio failed quality check: %v", report.Reasons)
+	}
+
 	if !exists {
 		// 创建新的说话人数据
 		speakerData = &SpeakerData{
 			ID:          speakerID,
 			Name:        speakerName,
-			Embeddings:  [][]float32{},
+			Embeddings:  [][]float32{embedding},
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
-			SampleCount: 0,
+			SampleCount: 1,
 		}
 		m.database.Speakers[speakerID] = speakerData
-	}
 
-	// 添加新的嵌入向量
-	speakerData.Embeddings = append(speakerData.Embeddings, embedding)
-	speakerData.UpdatedAt = time.Now()
-	speakerData.SampleCount++
-	speakerData.Name = speakerName // 更新名称
+		if err := m.store.Upsert(speakerData); err != nil {
+			return report, fmt.Errorf("failed to save speaker: %v", err)
+		}
+	} else {
+		// 已存在的说话人默认只追加一条embedding，避免整表重写；重命名的说话人退化为
+		// Upsert做整条记录重写。追加后的最新状态统一以store层返回的记录为准——manager
+		// 不在调用store之前就预先修改speakerData，因为JSON驱动的LoadAll与内部db共享
+		// 同一个*SpeakerData指针，预先修改再让store内部重复追加会导致embedding/
+		// SampleCount被计两次
+		nameChanged := speakerData.Name != speakerName
+
+		if nameChanged {
+			updated := &SpeakerData{
+				ID:          speakerData.ID,
+				Name:        speakerName,
+				Embeddings:  append(speakerData.Embeddings, embedding),
+				CreatedAt:   speakerData.CreatedAt,
+				UpdatedAt:   time.Now(),
+				SampleCount: speakerData.SampleCount + 1,
+			}
+			if err := m.store.Upsert(updated); err != nil {
+				return report, fmt.Errorf("failed to save speaker: %v", err)
+			}
+			speakerData = updated
+		} else {
+			updated, err := m.store.AppendEmbedding(speakerID, embedding)
+			if err != nil {
+				return report, fmt.Errorf("failed to save embedding: %v", err)
+			}
+			speakerData = updated
+		}
+		m.database.Speakers[speakerID] = speakerData
+	}
 
 	// 注册到内存管理器
 	success := m.manager.RegisterV(speakerID, speakerData.Embeddings)
 	if !success {
-		return fmt.Errorf("failed to register speaker to memory manager")
+		return report, fmt.Errorf("failed to register speaker to memory manager")
 	}
 
-	// 保存到文件
-	if err := m.saveDatabase(); err != nil {
-		return fmt.Errorf("failed to save database: %v", err)
+	if m.annIndex != nil {
+		m.annIndex.Insert(speakerID, speakerData.SampleCount-1, embedding)
 	}
 
 	logger.Infof("Successfully registered speaker %s (%s) with %d samples",
 		speakerID, speakerName, speakerData.SampleCount)
-	return nil
+	return report, nil
 }
 
 // IdentifySpeaker 识别声纹（直接使用内存中的数据进行高效对比）
@@ -305,8 +371,20 @@ func (m *Manager) IdentifySpeaker(audioData []float32, sampleRate int) (*Identif
 		return nil, fmt.Errorf("failed to extract embedding: %v", err)
 	}
 
-	// 在内存管理器中搜索最佳匹配（已加载的声纹数据直接内存对比）
-	speakerID := m.manager.Search(embedding, m.threshold)
+	var speakerID string
+	if m.annIndex != nil {
+		// ANN索引命中后仍用精确余弦相似度复核，避免近似搜索带来的误判
+		if candidateID, _, found := m.annIndex.Search(embedding, 0); found {
+			if speakerData, exists := m.database.Speakers[candidateID]; exists {
+				if m.calculateSimilarity(embedding, speakerData.Embeddings) >= m.threshold {
+					speakerID = candidateID
+				}
+			}
+		}
+	} else {
+		// 精确路径：在内存管理器中做线性扫描搜索最佳匹配
+		speakerID = m.manager.Search(embedding, m.threshold)
+	}
 
 	result := &IdentifyResult{
 		Identified:  false,
@@ -400,9 +478,13 @@ func (m *Manager) DeleteSpeaker(speakerID string) error {
 	// 从内存管理器删除
 	m.manager.Remove(speakerID)
 
-	// 保存到文件
-	if err := m.saveDatabase(); err != nil {
-		return fmt.Errorf("failed to save database: %v", err)
+	if m.annIndex != nil {
+		m.annIndex.Tombstone(speakerID)
+	}
+
+	// 从存储后端删除
+	if err := m.store.Delete(speakerID); err != nil {
+		return fmt.Errorf("failed to delete speaker from store: %v", err)
 	}
 
 	logger.Infof("Successfully deleted speaker %s", speakerID)
@@ -0,0 +1,153 @@
+package speaker
+
+import (
+	"math"
+	"sort"
+)
+
+// QualityThresholds 声纹录入前置质量检查的阈值配置
+type QualityThresholds struct {
+	MinVoicedSeconds       float32 // 最少有效语音时长
+	MinSNRDb               float32 // 最低信噪比（dB）
+	MaxClippingRatio       float32 // 允许的最大削波采样点占比
+	MaxDuplicateSimilarity float32 // 与已有样本余弦相似度超过该值视为重复样本
+	FrameSizeSamples       int     // 能量/过零分析的帧长
+}
+
+// EnrollmentQualityReport 录入前置质量检查报告，原样返回给客户端用于展示具体问题
+type EnrollmentQualityReport struct {
+	Passed        bool     `json:"passed"`
+	VoicedSeconds float32  `json:"voiced_seconds"`
+	SNRDb         float32  `json:"snr_db"`
+	ClippingRatio float32  `json:"clipping_ratio"`
+	MaxSimilarity float32  `json:"max_similarity_to_existing"`
+	IsDuplicate   bool     `json:"is_duplicate"`
+	Reasons       []string `json:"reasons,omitempty"`
+}
+
+// qualityThresholdsFromConfig 从Config解析质量检查阈值，未配置时使用合理默认值
+func qualityThresholdsFromConfig(config *Config) QualityThresholds {
+	thresholds := QualityThresholds{
+		MinVoicedSeconds:       config.MinVoicedSeconds,
+		MinSNRDb:               config.MinSNRDb,
+		MaxClippingRatio:       config.MaxClippingRatio,
+		MaxDuplicateSimilarity: config.MaxDuplicateSimilarity,
+		FrameSizeSamples:       400,
+	}
+
+	if thresholds.MinVoicedSeconds <= 0 {
+		thresholds.MinVoicedSeconds = 1.5
+	}
+	if thresholds.MaxClippingRatio <= 0 {
+		thresholds.MaxClippingRatio = 0.01
+	}
+	if thresholds.MaxDuplicateSimilarity <= 0 {
+		thresholds.MaxDuplicateSimilarity = 0.99
+	}
+
+	return thresholds
+}
+
+// frameEnergies 将音频按帧切分并计算每帧的均方根能量
+func frameEnergies(audioData []float32, frameSize int) []float32 {
+	if frameSize <= 0 {
+		frameSize = 400
+	}
+
+	energies := make([]float32, 0, len(audioData)/frameSize+1)
+	for start := 0; start < len(audioData); start += frameSize {
+		end := start + frameSize
+		if end > len(audioData) {
+			end = len(audioData)
+		}
+
+		var sumSq float64
+		for _, s := range audioData[start:end] {
+			sumSq += float64(s) * float64(s)
+		}
+		rms := math.Sqrt(sumSq / float64(end-start))
+		energies = append(energies, float32(rms))
+	}
+	return energies
+}
+
+// assessEnrollmentQuality 对一段待录入的音频做质量评估：
+// voiced时长（基于能量门限的粗略VAD）、SNR估计（最低10%能量帧视为噪声底）、
+// 削波比例，以及与该说话人已有embedding的最大相似度（由调用方预先算好传入，判断是否为重复样本）
+func assessEnrollmentQuality(audioData []float32, sampleRate int, maxSimilarityToExisting float32, hasExisting bool, thresholds QualityThresholds) *EnrollmentQualityReport {
+	report := &EnrollmentQualityReport{Passed: true}
+
+	if len(audioData) == 0 {
+		report.Passed = false
+		report.Reasons = append(report.Reasons, "empty audio")
+		return report
+	}
+
+	frameSize := thresholds.FrameSizeSamples
+	if frameSize <= 0 {
+		frameSize = 400
+	}
+	energies := frameEnergies(audioData, frameSize)
+
+	sorted := append([]float32{}, energies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	noiseFrameCount := len(sorted) / 10
+	if noiseFrameCount == 0 {
+		noiseFrameCount = 1
+	}
+
+	var noiseFloor float64
+	for _, e := range sorted[:noiseFrameCount] {
+		noiseFloor += float64(e)
+	}
+	noiseFloor /= float64(noiseFrameCount)
+
+	// 将能量高于噪声底数倍的帧视为"有声"帧，用于估计有效语音时长
+	voicedThreshold := float32(noiseFloor * 3)
+	voicedFrames := 0
+	var voicedEnergySum float64
+	for _, e := range energies {
+		if e > voicedThreshold {
+			voicedFrames++
+			voicedEnergySum += float64(e)
+		}
+	}
+
+	report.VoicedSeconds = float32(voicedFrames*frameSize) / float32(sampleRate)
+	if report.VoicedSeconds < thresholds.MinVoicedSeconds {
+		report.Passed = false
+		report.Reasons = append(report.Reasons, "audio too short or mostly silence")
+	}
+
+	if voicedFrames > 0 && noiseFloor > 0 {
+		voicedMeanEnergy := voicedEnergySum / float64(voicedFrames)
+		report.SNRDb = float32(20 * math.Log10(voicedMeanEnergy/noiseFloor))
+	}
+	if report.SNRDb < thresholds.MinSNRDb {
+		report.Passed = false
+		report.Reasons = append(report.Reasons, "signal-to-noise ratio too low")
+	}
+
+	clippedCount := 0
+	for _, s := range audioData {
+		if s >= 0.98 || s <= -0.98 {
+			clippedCount++
+		}
+	}
+	report.ClippingRatio = float32(clippedCount) / float32(len(audioData))
+	if report.ClippingRatio > thresholds.MaxClippingRatio {
+		report.Passed = false
+		report.Reasons = append(report.Reasons, "audio is clipped")
+	}
+
+	if hasExisting {
+		report.MaxSimilarity = maxSimilarityToExisting
+		if report.MaxSimilarity > thresholds.MaxDuplicateSimilarity {
+			report.IsDuplicate = true
+			report.Passed = false
+			report.Reasons = append(report.Reasons, "too similar to an existing sample")
+		}
+	}
+
+	return report
+}
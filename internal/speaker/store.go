@@ -0,0 +1,185 @@
+package speaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SpeakerStore 声纹持久化存储接口，屏蔽具体的存储介质
+type SpeakerStore interface {
+	// LoadAll 启动时加载全部声纹数据
+	LoadAll() (map[string]*SpeakerData, error)
+
+	// Upsert 新增或更新一个说话人（含其全部embedding）
+	Upsert(data *SpeakerData) error
+
+	// Delete 删除一个说话人
+	Delete(speakerID string) error
+
+	// GetByID 按ID获取单个说话人
+	GetByID(speakerID string) (*SpeakerData, error)
+
+	// AppendEmbedding 向已存在的说话人追加一个embedding，避免整表重写；返回追加后的
+	// 最新记录，调用方应以此为准更新自己持有的引用，而不是自行预先修改后再调用本方法，
+	// 否则JSON驱动下LoadAll共享指针会导致embedding/SampleCount被重复累加
+	AppendEmbedding(speakerID string, embedding []float32) (*SpeakerData, error)
+
+	// Close 释放存储层持有的资源（连接池等）
+	Close() error
+}
+
+// NewStore 根据配置创建对应的存储后端
+func NewStore(config *Config) (SpeakerStore, error) {
+	switch config.StorageDriver {
+	case "", StorageDriverJSON:
+		return NewJSONFileStore(config.DataDir)
+	case StorageDriverMySQL:
+		return NewMySQLStore(config.DSN)
+	case StorageDriverRedis:
+		return NewRedisStore(config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", config.StorageDriver)
+	}
+}
+
+const (
+	StorageDriverJSON  = "json"
+	StorageDriverMySQL = "mysql"
+	StorageDriverRedis = "redis"
+)
+
+// JSONFileStore 单文件JSON存储，兼容早期版本的 speaker.json 布局
+type JSONFileStore struct {
+	mu     sync.Mutex
+	dbPath string
+	db     *SpeakerDatabase
+}
+
+// NewJSONFileStore 创建JSON文件存储
+func NewJSONFileStore(dataDir string) (*JSONFileStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	store := &JSONFileStore{
+		dbPath: filepath.Join(dataDir, "speaker.json"),
+	}
+
+	if err := store.load(); err != nil {
+		store.db = &SpeakerDatabase{
+			Speakers:  make(map[string]*SpeakerData),
+			Version:   "1.0.0",
+			UpdatedAt: time.Now(),
+		}
+	}
+
+	return store, nil
+}
+
+func (s *JSONFileStore) load() error {
+	if _, err := os.Stat(s.dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("database file does not exist")
+	}
+
+	data, err := ioutil.ReadFile(s.dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to read database file: %v", err)
+	}
+
+	var db SpeakerDatabase
+	if err := json.Unmarshal(data, &db); err != nil {
+		return fmt.Errorf("failed to unmarshal database: %v", err)
+	}
+
+	s.db = &db
+	return nil
+}
+
+// save 将整个数据库重写到磁盘（单文件存储的固有限制：Upsert/Delete/AppendEmbedding都会整文件重写）
+func (s *JSONFileStore) save() error {
+	s.db.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(s.db, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal database: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.dbPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write database file: %v", err)
+	}
+
+	return nil
+}
+
+// LoadAll 加载全部声纹数据
+func (s *JSONFileStore) LoadAll() (map[string]*SpeakerData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]*SpeakerData, len(s.db.Speakers))
+	for id, data := range s.db.Speakers {
+		result[id] = data
+	}
+	return result, nil
+}
+
+// Upsert 新增或更新一个说话人
+func (s *JSONFileStore) Upsert(data *SpeakerData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.db.Speakers[data.ID] = data
+	return s.save()
+}
+
+// Delete 删除一个说话人
+func (s *JSONFileStore) Delete(speakerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.db.Speakers, speakerID)
+	return s.save()
+}
+
+// GetByID 按ID获取单个说话人
+func (s *JSONFileStore) GetByID(speakerID string) (*SpeakerData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, exists := s.db.Speakers[speakerID]
+	if !exists {
+		return nil, fmt.Errorf("speaker %s not found", speakerID)
+	}
+	return data, nil
+}
+
+// AppendEmbedding 向已存在的说话人追加一个embedding
+func (s *JSONFileStore) AppendEmbedding(speakerID string, embedding []float32) (*SpeakerData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, exists := s.db.Speakers[speakerID]
+	if !exists {
+		return nil, fmt.Errorf("speaker %s not found", speakerID)
+	}
+
+	data.Embeddings = append(data.Embeddings, embedding)
+	data.SampleCount++
+	data.UpdatedAt = time.Now()
+
+	// 单文件存储没有行级更新概念，追加依旧需要整文件重写
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Close JSON文件存储没有需要释放的资源
+func (s *JSONFileStore) Close() error {
+	return nil
+}
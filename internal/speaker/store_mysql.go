@@ -0,0 +1,223 @@
+package speaker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"asr_server/internal/logger"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// speakerRow 说话人表，对应 speakers
+type speakerRow struct {
+	ID          string `gorm:"primaryKey;size:128"`
+	Name        string `gorm:"size:255"`
+	SampleCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (speakerRow) TableName() string { return "speakers" }
+
+// embeddingRow 声纹特征表，对应 speaker_embeddings，一个说话人对应多行
+type embeddingRow struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	SpeakerID string `gorm:"size:128;index"`
+	Vector    []byte `gorm:"type:blob"` // float32切片按小端序打包存储
+	CreatedAt time.Time
+}
+
+func (embeddingRow) TableName() string { return "speaker_embeddings" }
+
+// MySQLStore 基于GORM的MySQL存储后端
+type MySQLStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLStore 创建MySQL存储，dsn形如 user:pass@tcp(host:3306)/dbname?parseTime=true
+func NewMySQLStore(dsn string) (*MySQLStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("mysql dsn is required")
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect mysql: %v", err)
+	}
+
+	if err := db.AutoMigrate(&speakerRow{}, &embeddingRow{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate speaker tables: %v", err)
+	}
+
+	logger.Infof("✅ Connected to MySQL speaker store")
+	return &MySQLStore{db: db}, nil
+}
+
+// LoadAll 加载全部声纹数据
+func (s *MySQLStore) LoadAll() (map[string]*SpeakerData, error) {
+	var speakers []speakerRow
+	if err := s.db.Find(&speakers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load speakers: %v", err)
+	}
+
+	var embeddings []embeddingRow
+	if err := s.db.Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load embeddings: %v", err)
+	}
+
+	result := make(map[string]*SpeakerData, len(speakers))
+	for _, row := range speakers {
+		result[row.ID] = &SpeakerData{
+			ID:          row.ID,
+			Name:        row.Name,
+			Embeddings:  [][]float32{},
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			SampleCount: row.SampleCount,
+		}
+	}
+
+	for _, row := range embeddings {
+		data, exists := result[row.SpeakerID]
+		if !exists {
+			continue
+		}
+		data.Embeddings = append(data.Embeddings, unpackEmbedding(row.Vector))
+	}
+
+	return result, nil
+}
+
+// Upsert 新增或更新一个说话人（整条记录，包括全部embedding）
+func (s *MySQLStore) Upsert(data *SpeakerData) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		row := speakerRow{
+			ID:          data.ID,
+			Name:        data.Name,
+			SampleCount: data.SampleCount,
+			CreatedAt:   data.CreatedAt,
+			UpdatedAt:   data.UpdatedAt,
+		}
+		if err := tx.Save(&row).Error; err != nil {
+			return fmt.Errorf("failed to upsert speaker row: %v", err)
+		}
+
+		if err := tx.Where("speaker_id = ?", data.ID).Delete(&embeddingRow{}).Error; err != nil {
+			return fmt.Errorf("failed to clear embeddings: %v", err)
+		}
+
+		for _, embedding := range data.Embeddings {
+			embRow := embeddingRow{
+				SpeakerID: data.ID,
+				Vector:    packEmbedding(embedding),
+				CreatedAt: time.Now(),
+			}
+			if err := tx.Create(&embRow).Error; err != nil {
+				return fmt.Errorf("failed to insert embedding: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete 删除一个说话人及其全部embedding
+func (s *MySQLStore) Delete(speakerID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("speaker_id = ?", speakerID).Delete(&embeddingRow{}).Error; err != nil {
+			return fmt.Errorf("failed to delete embeddings: %v", err)
+		}
+		if err := tx.Where("id = ?", speakerID).Delete(&speakerRow{}).Error; err != nil {
+			return fmt.Errorf("failed to delete speaker: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetByID 按ID获取单个说话人
+func (s *MySQLStore) GetByID(speakerID string) (*SpeakerData, error) {
+	var row speakerRow
+	if err := s.db.First(&row, "id = ?", speakerID).Error; err != nil {
+		return nil, fmt.Errorf("speaker %s not found: %v", speakerID, err)
+	}
+
+	var embeddings []embeddingRow
+	if err := s.db.Where("speaker_id = ?", speakerID).Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load embeddings for %s: %v", speakerID, err)
+	}
+
+	data := &SpeakerData{
+		ID:          row.ID,
+		Name:        row.Name,
+		Embeddings:  make([][]float32, 0, len(embeddings)),
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		SampleCount: row.SampleCount,
+	}
+	for _, embRow := range embeddings {
+		data.Embeddings = append(data.Embeddings, unpackEmbedding(embRow.Vector))
+	}
+
+	return data, nil
+}
+
+// AppendEmbedding 向已存在的说话人追加一行embedding，无需重写其余数据；返回追加后的
+// 最新记录，与SpeakerStore接口约定保持一致
+func (s *MySQLStore) AppendEmbedding(speakerID string, embedding []float32) (*SpeakerData, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		embRow := embeddingRow{
+			SpeakerID: speakerID,
+			Vector:    packEmbedding(embedding),
+			CreatedAt: time.Now(),
+		}
+		if err := tx.Create(&embRow).Error; err != nil {
+			return fmt.Errorf("failed to append embedding: %v", err)
+		}
+
+		return tx.Model(&speakerRow{}).Where("id = ?", speakerID).
+			Updates(map[string]interface{}{
+				"sample_count": gorm.Expr("sample_count + 1"),
+				"updated_at":   time.Now(),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(speakerID)
+}
+
+// Close 关闭底层连接池
+func (s *MySQLStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// packEmbedding 将float32切片按小端序打包成字节数组，便于写入BLOB列
+func packEmbedding(embedding []float32) []byte {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		bits := math.Float32bits(v)
+		buf[i*4] = byte(bits)
+		buf[i*4+1] = byte(bits >> 8)
+		buf[i*4+2] = byte(bits >> 16)
+		buf[i*4+3] = byte(bits >> 24)
+	}
+	return buf
+}
+
+// unpackEmbedding 将BLOB列还原为float32切片
+func unpackEmbedding(buf []byte) []float32 {
+	n := len(buf) / 4
+	embedding := make([]float32, n)
+	for i := 0; i < n; i++ {
+		bits := uint32(buf[i*4]) | uint32(buf[i*4+1])<<8 | uint32(buf[i*4+2])<<16 | uint32(buf[i*4+3])<<24
+		embedding[i] = math.Float32frombits(bits)
+	}
+	return embedding
+}
@@ -0,0 +1,71 @@
+// Package tracing 按config.Observability配置初始化全局OTel TracerProvider，
+// 并通过Tracer()暴露一个包级访问器，供HandleWebSocket、session.Manager等按需
+// 创建span，与internal/logger的Init+全局访问器是同一种组织方式
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"asr_server/config"
+)
+
+const tracerName = "asr_server"
+const defaultServiceName = "asr_server"
+
+// Init 按cfg.Observability初始化全局TracerProvider，返回的shutdown应在进程退出前
+// 调用一次以flush尚未导出的span。Enabled为false时跳过初始化：otel.Tracer在没有
+// 注册过TracerProvider时本就返回no-op实现，调用方（Tracer()的使用者）不需要关心
+// 这两种情况的区别
+func Init(cfg *config.Config) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+
+	obs := cfg.Observability
+	if !obs.Enabled {
+		return noop, nil
+	}
+	if obs.OTLPEndpoint == "" {
+		return nil, fmt.Errorf("observability.otlp_endpoint must not be empty when observability.enabled is true")
+	}
+
+	serviceName := obs.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background(),
+		otlptracegrpc.WithEndpoint(obs.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OTel resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Tracer 返回全局tracer；Init未调用或observability.enabled为false时，otel默认的
+// no-op TracerProvider会让Start返回一个不做任何事的span，调用方不需要做判空处理
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
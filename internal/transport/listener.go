@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+)
+
+// ListenerWrapper 包装一个net.Listener，使TLS配置能在运行时原子替换（证书/私钥/
+// 客户端CA轮换）而不必重建监听socket或断开已经建立的连接：每次Accept都按"当前"
+// 生效的*tls.Config决定是否以及如何做TLS握手，做法参考oragono对监听器的处理方式
+type ListenerWrapper struct {
+	netListener net.Listener
+	tlsConfig   atomic.Value // 存*tls.Config；未Store或Store(nil)时退化为明文TCP
+}
+
+// NewListenerWrapper 创建ListenerWrapper；tlsConfig为nil时以明文TCP提供服务
+func NewListenerWrapper(netListener net.Listener, tlsConfig *tls.Config) *ListenerWrapper {
+	w := &ListenerWrapper{netListener: netListener}
+	if tlsConfig != nil {
+		w.tlsConfig.Store(tlsConfig)
+	}
+	return w
+}
+
+// Accept 实现net.Listener。TLS配置在Accept时刻读取，使证书热重载对此后到达的
+// 新连接立即生效，而不影响已经握手完成的旧连接
+func (w *ListenerWrapper) Accept() (net.Conn, error) {
+	conn, err := w.netListener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if cfg, ok := w.tlsConfig.Load().(*tls.Config); ok && cfg != nil {
+		return tls.Server(conn, cfg), nil
+	}
+	return conn, nil
+}
+
+// Close 实现net.Listener
+func (w *ListenerWrapper) Close() error {
+	return w.netListener.Close()
+}
+
+// Addr 实现net.Listener
+func (w *ListenerWrapper) Addr() net.Addr {
+	return w.netListener.Addr()
+}
+
+// Store 原子替换当前生效的TLS配置；传nil会让此后的新连接退化为明文TCP，
+// 已经建立的连接不受影响
+func (w *ListenerWrapper) Store(cfg *tls.Config) {
+	w.tlsConfig.Store(cfg)
+}
+
+// IsTLSEnabled 返回当前是否正在以TLS方式接受新连接
+func (w *ListenerWrapper) IsTLSEnabled() bool {
+	cfg, ok := w.tlsConfig.Load().(*tls.Config)
+	return ok && cfg != nil
+}
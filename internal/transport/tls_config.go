@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"asr_server/config"
+)
+
+// tlsVersions 把server.tls.min_version的字符串形式映射到crypto/tls的版本常量
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// BuildTLSConfig 按当前生效的server.tls配置构建*tls.Config：加载证书/私钥，
+// client_ca_file非空时额外加载客户端CA并开启mTLS校验，应用最低TLS版本与密码
+// 套件白名单。cfg.Enabled为false时返回(nil, nil)，调用方应以nil表示退化为明文TCP。
+// 不接收参数而是直接读config.Load()，使InitApp的初次构建和hot-reload回调的重新
+// 构建走同一份逻辑
+func BuildTLSConfig() (*tls.Config, error) {
+	cfg := config.Load().Server.TLS
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate pair: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.MinVersion != "" {
+		version, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported server.tls.min_version: %s", cfg.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(cfg.CipherSuites) > 0 {
+		suites, err := resolveCipherSuites(cfg.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.CipherSuites = suites
+	}
+
+	if cfg.ClientCAFile != "" {
+		caBytes, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("failed to parse client CA file: %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		if cfg.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// resolveCipherSuites 把密码套件名称（crypto/tls标准命名，如"TLS_AES_128_GCM_SHA256"）
+// 解析为对应ID，未知名称视为配置错误
+func resolveCipherSuites(names []string) ([]uint16, error) {
+	available := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		available[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		available[s.Name] = s.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown server.tls.cipher_suites entry: %s", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
@@ -1,114 +1,289 @@
-package ws
-
-import (
-	"asr_server/config"
-	"asr_server/internal/logger"
-	"asr_server/internal/session"
-	"crypto/rand"
-	"encoding/hex"
-	"net/http"
-	"time"
-
-	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
-
-	"github.com/gorilla/websocket"
-)
-
-// Upgrader 用于升级 WebSocket 连接
-var Upgrader = websocket.Upgrader{
-	CheckOrigin:       func(r *http.Request) bool { return true },
-	ReadBufferSize:    config.GlobalConfig.Server.WebSocket.ReadBufferSize,
-	WriteBufferSize:   config.GlobalConfig.Server.WebSocket.WriteBufferSize,
-	EnableCompression: config.GlobalConfig.Server.WebSocket.EnableCompression,
-}
-
-// GenerateSessionID 生成会话ID
-func GenerateSessionID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
-}
-
-// HandleWebSocket 处理 WebSocket 连接
-// 依赖注入 sessionManager, globalRecognizer
-func HandleWebSocket(w http.ResponseWriter, r *http.Request, sessionManager *session.Manager, globalRecognizer *sherpa.OfflineRecognizer) {
-	conn, err := Upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		logger.Errorf("WebSocket upgrade failed: %v", err)
-		return
-	}
-
-	wsConfig := config.GlobalConfig.Server.WebSocket
-
-	if wsConfig.ReadTimeout > 0 {
-		conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
-	}
-
-	sessionID := GenerateSessionID()
-
-	// 创建会话
-	sess, err := sessionManager.CreateSession(sessionID, conn)
-	if err != nil {
-		logger.Errorf("Failed to create session, session_id=%s, error=%v", sessionID, err)
-		conn.Close()
-		return
-	}
-
-	defer func() {
-		sessionManager.RemoveSession(sessionID)
-		logger.Infof("WebSocket connection closed, session_id=%s", sessionID)
-	}()
-
-	logger.Infof("New WebSocket connection established, session_id=%s", sessionID)
-
-	// 发送连接确认
-	if sess != nil {
-		select {
-		case sess.SendQueue <- map[string]interface{}{
-			"type":       "connection",
-			"message":    "WebSocket connected, ready for audio",
-			"session_id": sessionID,
-		}:
-		default:
-			logger.Warnf("Session send queue is full, dropping connection confirmation")
-		}
-	}
-
-	// 处理消息
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			logger.Warnf("WebSocket read error")
-			break
-		}
-
-		// 每次收到消息都刷新读超时
-		if wsConfig.ReadTimeout > 0 {
-			conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
-		}
-
-		// 检查消息大小
-		if wsConfig.MaxMessageSize > 0 && len(message) > wsConfig.MaxMessageSize {
-			logger.Warnf("Message too large, closing connection")
-			break
-		}
-
-		// 处理音频数据
-		if len(message) > 0 {
-			if err := sessionManager.ProcessAudioData(sessionID, message); err != nil {
-				logger.Errorf("Failed to process audio data, session_id=%s, error=%v", sessionID, err)
-				// 通过session的SendQueue发送错误消息
-				if sess != nil {
-					select {
-					case sess.SendQueue <- map[string]interface{}{
-						"type":    "error",
-						"message": err.Error(),
-					}:
-					default:
-						logger.Warnf("Session send queue is full, dropping error message")
-					}
-				}
-			}
-		}
-	}
-}
+package ws
+
+import (
+	"asr_server/config"
+	"asr_server/internal/codec"
+	"asr_server/internal/logger"
+	"asr_server/internal/metrics"
+	"asr_server/internal/middleware"
+	"asr_server/internal/session"
+	"asr_server/internal/tracing"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// controlMessage 文本帧携带的控制消息，目前支持房间订阅/取消订阅以及编解码协商
+type controlMessage struct {
+	Type       string `json:"type"`
+	Room       string `json:"room"`
+	Codec      string `json:"codec"`
+	SampleRate int    `json:"sample_rate"`
+}
+
+// subprotocolCodecs 列出可通过WebSocket子协议直接声明的编解码器，客户端也可以改用
+// 首帧"config"控制消息协商，两者效果等价，后者优先级更高（连接建立后仍可再协商一次）
+var subprotocolCodecs = []struct {
+	subprotocol string
+	codecName   string
+}{
+	{"asr.pcm16", codec.PCM16LE},
+	{"asr.mulaw", codec.PCMMuLaw},
+	{"asr.alaw", codec.PCMALaw},
+	{"asr.opus", codec.Opus},
+	{"asr.flac", codec.FLAC},
+}
+
+// connLimiter 由router.NewRouter在注册/ws路由之前通过SetConnectionLimiter注入，
+// CheckOrigin和HandleWebSocket里的准入控制共用同一个实例；为nil时保持"接受所有来源、
+// 不做准入限制"的历史行为
+var connLimiter *middleware.Limiter
+
+// SetConnectionLimiter 注入per-IP/CIDR连接限制器，必须在HandleWebSocket开始处理
+// 请求之前调用一次
+func SetConnectionLimiter(limiter *middleware.Limiter) {
+	connLimiter = limiter
+}
+
+// Upgrader 用于升级 WebSocket 连接
+var Upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		if connLimiter == nil {
+			return true
+		}
+		return connLimiter.CheckOrigin(r)
+	},
+	ReadBufferSize:    config.Load().Server.WebSocket.ReadBufferSize,
+	WriteBufferSize:   config.Load().Server.WebSocket.WriteBufferSize,
+	EnableCompression: config.Load().Server.WebSocket.EnableCompression,
+	Subprotocols:      subprotocolNames(),
+}
+
+func subprotocolNames() []string {
+	names := make([]string, 0, len(subprotocolCodecs))
+	for _, entry := range subprotocolCodecs {
+		names = append(names, entry.subprotocol)
+	}
+	return names
+}
+
+// codecForSubprotocol 将协商出的WebSocket子协议映射回编解码器名称
+func codecForSubprotocol(subprotocol string) (string, bool) {
+	for _, entry := range subprotocolCodecs {
+		if entry.subprotocol == subprotocol {
+			return entry.codecName, true
+		}
+	}
+	return "", false
+}
+
+// GenerateSessionID 生成会话ID
+func GenerateSessionID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// HandleWebSocket 处理 WebSocket 连接
+// 依赖注入 sessionManager；识别引擎/VAD池由sessionManager内部按会话路由，这里无需关心
+func HandleWebSocket(w http.ResponseWriter, r *http.Request, sessionManager *session.Manager) {
+	remoteAddr := r.RemoteAddr
+	forwardedFor := r.Header.Get("X-Forwarded-For")
+
+	// 准入控制必须在升级为WebSocket之前做：一旦Upgrade成功就很难再干净地拒绝连接，
+	// 而被拒绝的请求应该收到一个普通HTTP 403/429响应而不是被升级后再踢掉
+	if connLimiter != nil {
+		if err := connLimiter.Acquire(remoteAddr, forwardedFor); err != nil {
+			status := http.StatusTooManyRequests
+			if errors.Is(err, middleware.ErrConnectionDenied) {
+				status = http.StatusForbidden
+			}
+			logger.Warnf("WebSocket connection rejected, remote_addr=%s, error=%v", remoteAddr, err)
+			metrics.WSUpgradeFailuresTotal.Inc()
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	conn, err := Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Errorf("WebSocket upgrade failed: %v", err)
+		metrics.WSUpgradeFailuresTotal.Inc()
+		if connLimiter != nil {
+			connLimiter.Release(remoteAddr, forwardedFor)
+		}
+		return
+	}
+	metrics.WSUpgradesTotal.Inc()
+	if connLimiter != nil {
+		defer connLimiter.Release(remoteAddr, forwardedFor)
+	}
+
+	wsConfig := config.Load().Server.WebSocket
+
+	if wsConfig.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
+	}
+
+	// 断线重连：客户端在升级URL上带resume/token时先尝试把这条新连接rebind到一个仍在
+	// drain宽限期内的既有会话上，从而延续其VAD实例、累积音频段与声纹分离状态；
+	// 校验失败或会话已经被销毁则退化为下面的"分配全新会话"路径
+	sessionID := GenerateSessionID()
+	var sess *session.Session
+
+	if resumeID, token := r.URL.Query().Get("resume"), r.URL.Query().Get("token"); resumeID != "" && token != "" {
+		if resumed, ok := sessionManager.TryResume(resumeID, token, conn); ok {
+			sessionID = resumeID
+			sess = resumed
+			logger.Infof("Session %s resumed on reconnect, remote_addr=%s", sessionID, remoteAddr)
+		} else {
+			logger.Warnf("Session %s: resume failed, allocating a new session instead", resumeID)
+		}
+	}
+
+	if sess == nil {
+		sess, err = sessionManager.CreateSession(sessionID, conn)
+		if err != nil {
+			logger.Errorf("Failed to create session, session_id=%s, error=%v", sessionID, err)
+			conn.Close()
+			return
+		}
+	}
+
+	// connCtx承载一个覆盖整条连接生命周期的根span，session_id作为属性打在上面；
+	// 每条音频消息的ProcessAudioData都以它为父span创建自己的子span
+	connCtx, connSpan := tracing.Tracer().Start(r.Context(), "ws.connection")
+	connSpan.SetAttributes(attribute.String("session_id", sessionID))
+	defer connSpan.End()
+
+	defer func() {
+		sessionManager.RemoveSession(sessionID)
+		logger.Infof("WebSocket connection closed, session_id=%s", sessionID)
+	}()
+
+	logger.Infof("New WebSocket connection established, session_id=%s", sessionID)
+
+	// mTLS：r.TLS仅在底层连接经ListenerWrapper做了TLS握手时非空；PeerCertificates
+	// 仅在server.tls.client_ca_file开启且客户端提供了证书时非空，供下游speaker/ASR
+	// 等handler据此做基于证书身份的鉴权
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		peerCert := r.TLS.PeerCertificates[0]
+		sessionManager.SetPeerIdentity(sessionID, peerCert.Subject.String(), peerCert.Subject.CommonName)
+		logger.Infof("Session %s: authenticated via client certificate, cn=%s", sessionID, peerCert.Subject.CommonName)
+	}
+
+	// 通过WebSocket子协议声明的编解码器视为初始协商结果，客户端仍可通过首帧config控制消息覆盖
+	if codecName, ok := codecForSubprotocol(conn.Subprotocol()); ok {
+		if err := sessionManager.ConfigureCodec(sessionID, codecName, 0); err != nil {
+			logger.Warnf("Session %s: failed to apply subprotocol codec %s: %v", sessionID, codecName, err)
+		}
+	}
+
+	// 发送连接确认；resume_token仅在Session.WithResumption配置了secret时非空，
+	// 客户端应保存它以便断线后通过?resume=<session_id>&token=<resume_token>重连
+	if sess != nil {
+		connMsg := map[string]interface{}{
+			"type":       "connection",
+			"message":    "WebSocket connected, ready for audio",
+			"session_id": sessionID,
+		}
+		if token, ok := sessionManager.IssueResumeToken(sessionID); ok {
+			connMsg["resume_token"] = token
+		}
+		select {
+		case sess.SendQueue <- connMsg:
+		default:
+			logger.Warnf("Session send queue is full, dropping connection confirmation")
+		}
+	}
+
+	// 处理消息
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			logger.Warnf("WebSocket read error")
+			break
+		}
+
+		// 每次收到消息都刷新读超时
+		if wsConfig.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
+		}
+
+		// 检查消息大小
+		if wsConfig.MaxMessageSize > 0 && len(message) > wsConfig.MaxMessageSize {
+			logger.Warnf("Message too large, closing connection")
+			break
+		}
+
+		if len(message) == 0 {
+			continue
+		}
+
+		// 文本帧视为控制消息（如房间订阅），二进制帧视为音频数据
+		if messageType == websocket.TextMessage {
+			handleControlMessage(sessionManager, sessionID, message)
+			continue
+		}
+
+		metrics.WSMessageSizeBytes.Observe(float64(len(message)))
+
+		if err := sessionManager.ProcessAudioData(connCtx, sessionID, message); err != nil {
+			logger.Errorf("Failed to process audio data, session_id=%s, error=%v", sessionID, err)
+			// 通过session的SendQueue发送错误消息
+			if sess != nil {
+				select {
+				case sess.SendQueue <- map[string]interface{}{
+					"type":    "error",
+					"message": err.Error(),
+				}:
+				default:
+					logger.Warnf("Session send queue is full, dropping error message")
+				}
+			}
+		}
+	}
+}
+
+// handleControlMessage 解析并处理文本控制帧，目前支持 subscribe/unsubscribe 房间订阅
+// 以及 config 编解码协商
+func handleControlMessage(sessionManager *session.Manager, sessionID string, message []byte) {
+	var ctrl controlMessage
+	if err := json.Unmarshal(message, &ctrl); err != nil {
+		logger.Warnf("Session %s: invalid control message: %v", sessionID, err)
+		return
+	}
+
+	switch ctrl.Type {
+	case "subscribe":
+		if ctrl.Room == "" {
+			logger.Warnf("Session %s: subscribe message missing room", sessionID)
+			return
+		}
+		if err := sessionManager.JoinRoom(sessionID, ctrl.Room); err != nil {
+			logger.Warnf("Session %s: failed to subscribe to room %s: %v", sessionID, ctrl.Room, err)
+		}
+	case "unsubscribe":
+		if ctrl.Room == "" {
+			logger.Warnf("Session %s: unsubscribe message missing room", sessionID)
+			return
+		}
+		sessionManager.LeaveRoom(sessionID, ctrl.Room)
+	case "config":
+		if ctrl.Codec == "" {
+			logger.Warnf("Session %s: config message missing codec", sessionID)
+			return
+		}
+		if err := sessionManager.ConfigureCodec(sessionID, ctrl.Codec, ctrl.SampleRate); err != nil {
+			logger.Warnf("Session %s: failed to configure codec %s: %v", sessionID, ctrl.Codec, err)
+		}
+	default:
+		logger.Warnf("Session %s: unknown control message type: %s", sessionID, ctrl.Type)
+	}
+}
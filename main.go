@@ -3,18 +3,23 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"asr_server/config"
 	"asr_server/internal/bootstrap"
+	"asr_server/internal/grpcserver"
 	"asr_server/internal/logger"
 	"asr_server/internal/router"
+
+	"google.golang.org/grpc"
 )
 
+// defaultShutdownTimeout 未配置server.shutdown_timeout时的优雅关闭超时
+const defaultShutdownTimeout = 10 * time.Second
+
 func main() {
 
 	// 加载配置
@@ -23,22 +28,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	cfg := config.Load()
+
 	// 设置日志级别
 	logger.InitLoggerFromConfig(logger.LoggingConfig{
-		Level:      config.GlobalConfig.Logging.Level,
-		Format:     config.GlobalConfig.Logging.Format,
-		Output:     config.GlobalConfig.Logging.Output,
-		FilePath:   config.GlobalConfig.Logging.FilePath,
-		MaxSize:    config.GlobalConfig.Logging.MaxSize,
-		MaxBackups: config.GlobalConfig.Logging.MaxBackups,
-		MaxAge:     config.GlobalConfig.Logging.MaxAge,
-		Compress:   config.GlobalConfig.Logging.Compress,
+		Level:      cfg.Logging.Level,
+		Format:     cfg.Logging.Format,
+		Output:     cfg.Logging.Output,
+		FilePath:   cfg.Logging.FilePath,
+		MaxSize:    cfg.Logging.MaxSize,
+		MaxBackups: cfg.Logging.MaxBackups,
+		MaxAge:     cfg.Logging.MaxAge,
+		Compress:   cfg.Logging.Compress,
 	})
 	logger.Infof("✅ Configuration loaded")
 	config.PrintConfig()
 
 	// 初始化所有依赖
-	deps, err := bootstrap.InitApp(&config.GlobalConfig)
+	deps, err := bootstrap.InitApp(cfg)
 	if err != nil {
 		logger.Errorf("Failed to initialize app dependencies:%v", err)
 		os.Exit(1)
@@ -47,34 +54,74 @@ func main() {
 	// 统一注册所有路由
 	r := router.NewRouter(deps)
 
-	// 创建HTTP服务器
+	// 创建HTTP服务器；监听器已经在InitApp中bind并按server.tls包了一层ListenerWrapper，
+	// 这里用Serve而非ListenAndServe，使TLS配置可以在不重新bind端口的情况下热重载
 	server := &http.Server{
-		Addr:        fmt.Sprintf("%s:%d", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port),
 		Handler:     deps.RateLimiter.Middleware(r),
-		ReadTimeout: time.Duration(config.GlobalConfig.Server.ReadTimeout) * time.Second,
+		ReadTimeout: time.Duration(cfg.Server.ReadTimeout) * time.Second,
 	}
 
-	// 优雅关闭
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-quit
-		logger.Infof("🛑 Shutting down server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// 创建gRPC服务器（与HTTP服务共享同一套 speaker.Manager / 识别器实例）
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcSrv = grpc.NewServer()
+		grpcserver.NewServer(deps).Register(grpcSrv)
+
+		grpcAddr := fmt.Sprintf("%s:%d", cfg.GRPC.Host, cfg.GRPC.Port)
+		grpcListener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logger.Errorf("Failed to listen on gRPC address %s: %v", grpcAddr, err)
+			os.Exit(1)
+		}
+
+		go func() {
+			logger.Infof("🔌 gRPC server listening on %s", grpcAddr)
+			if err := grpcSrv.Serve(grpcListener); err != nil {
+				logger.Errorf("gRPC server error:%v", err)
+			}
+		}()
+	}
+
+	// 优雅关闭：第一次SIGINT/SIGTERM在shutdownTimeout内尽量排空HTTP/WS连接并销毁VAD池，
+	// 第二次缩短剩余等待时间，第三次直接强退；SIGQUIT可随时触发goroutine栈dump排查卡死点
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	bootstrap.Trap(func() {
+		// 先切到Draining：/readyz立即开始返回503，下游编排系统据此停止转发新的WebSocket
+		// 升级请求，而已建立的连接仍由下面的server.Shutdown继续排空
+		deps.State.Store(bootstrap.StateDraining)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Errorf("Server forced to shutdown:%v", err)
 		}
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
+		deps.VADPool.Shutdown()
+		deps.SessionManager.Shutdown()
+		if err := deps.TracerShutdown(ctx); err != nil {
+			logger.Errorf("Failed to flush tracer provider: %v", err)
+		}
+		deps.State.Store(bootstrap.StateStopped)
 		logger.Infof("✅ Server shutdown complete")
-	}()
+	}, shutdownTimeout)
+
+	deps.State.Store(bootstrap.StateReady)
 
-	logger.Infof("🌐 Listening on %s:%d", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port)
-	logger.Infof("🔗 WebSocket: ws://%s:%d/ws", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port)
-	logger.Infof("📊 Health check: http://%s:%d/health", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port)
-	logger.Infof("📈 Statistics: http://%s:%d/stats", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port)
-	logger.Infof("🧪 Test page: http://%s:%d/", config.GlobalConfig.Server.Host, config.GlobalConfig.Server.Port)
+	scheme, wsScheme := "http", "ws"
+	if deps.Listener.IsTLSEnabled() {
+		scheme, wsScheme = "https", "wss"
+	}
+	logger.Infof("🌐 Listening on %s:%d (tls=%v)", cfg.Server.Host, cfg.Server.Port, deps.Listener.IsTLSEnabled())
+	logger.Infof("🔗 WebSocket: %s://%s:%d/ws", wsScheme, cfg.Server.Host, cfg.Server.Port)
+	logger.Infof("📊 Health check: %s://%s:%d/health", scheme, cfg.Server.Host, cfg.Server.Port)
+	logger.Infof("📈 Statistics: %s://%s:%d/stats", scheme, cfg.Server.Host, cfg.Server.Port)
+	logger.Infof("🧪 Test page: %s://%s:%d/", scheme, cfg.Server.Host, cfg.Server.Port)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := server.Serve(deps.Listener); err != nil && err != http.ErrServerClosed {
 		logger.Errorf("Server error:%v", err)
 		os.Exit(1)
 	}